@@ -0,0 +1,28 @@
+package main
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	onlineMinutes           = envInt("TELEMETRY_ONLINE_MINUTES", 2)
+	dauHours                = envInt("TELEMETRY_DAU_HOURS", 24)
+	recentUsersLimit        = envInt("TELEMETRY_RECENT_USERS_LIMIT", 50)
+	dashboardRefreshSeconds = envInt("TELEMETRY_DASHBOARD_REFRESH_SECONDS", 60)
+)
+
+// queryIntInRange 读取整数查询参数, 缺省或超出 [min, max] 时使用默认值
+func queryIntInRange(c *gin.Context, key string, def, min, max int) int {
+	value, err := strconv.Atoi(c.Query(key))
+	if err != nil || value < min || value > max {
+		return def
+	}
+	return value
+}
+
+func onlineSince(minutes int) time.Time {
+	return time.Now().Add(-time.Duration(minutes) * time.Minute)
+}
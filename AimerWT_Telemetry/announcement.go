@@ -0,0 +1,257 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+var errInvalidSchedule = errors.New("invalid schedule time")
+
+// parseScheduleTime 解析控制台传入的时间, 支持 RFC3339 与 datetime-local 格式, 空字符串表示不限制;
+// 无法解析时返回错误, 避免输错的时间被当成不限制
+func parseScheduleTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02 15:04:05"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, errInvalidSchedule
+}
+
+func inSchedule(start, end *time.Time) bool {
+	now := time.Now()
+	if start != nil && now.Before(*start) {
+		return false
+	}
+	if end != nil && now.After(*end) {
+		return false
+	}
+	return true
+}
+
+// matchTarget 判断值是否命中逗号分隔的目标列表, 列表为空表示全部; 忽略大小写, 且 zh 可命中 zh-CN.
+// 公告、功能开关与不安全版本列表共用这一规则
+func matchTarget(list string, values ...string) bool {
+	if strings.TrimSpace(list) == "" {
+		return true
+	}
+	for _, item := range strings.Split(list, ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item == "" {
+			continue
+		}
+		for _, v := range values {
+			v = strings.ToLower(v)
+			if v == item || strings.HasPrefix(v, item+"-") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// targeted 判断通知是否面向该客户端: 版本范围 + 区域 + 系统 (系统可按 os 或 os_name 匹配, 例如 Windows 或 Windows 7)
+func targeted(scope, locales, osList string, record TelemetryRecord) bool {
+	if scope != "all" && scope != record.Version {
+		return false
+	}
+	return matchTarget(locales, record.Locale) && matchTarget(osList, record.OS, record.OSName)
+}
+
+// recordAnnouncement 将每次发布的更新提示写入历史表, 便于追溯, 返回记录 ID
+func recordAnnouncement(kind string, req map[string]any) uint {
+	a := Announcement{Kind: kind, Active: true}
+	a.Channel, _ = req["channel"].(string)
+	if err := applyAnnouncementFields(&a, kind, req); err != nil {
+		log.Printf("写入公告历史失败: %v", err)
+		return 0
+	}
+	if err := db.Create(&a).Error; err != nil {
+		log.Printf("写入公告历史失败: %v", err)
+	}
+	return a.ID
+}
+
+// applyAnnouncementFields 用请求中出现的字段覆盖公告, 未出现的字段保持原值
+func applyAnnouncementFields(a *Announcement, kind string, req map[string]any) error {
+	if val, ok := req["title"].(string); ok {
+		a.Title = val
+	}
+	if val, ok := req["content"].(string); ok {
+		a.Content = val
+	}
+	if val, ok := req["scope"].(string); ok {
+		a.Scope = val
+	}
+	if val, ok := req["locales"].(string); ok {
+		a.Locales = val
+	}
+	if val, ok := req["os"].(string); ok {
+		a.OS = val
+	}
+	if val, ok := req["url"].(string); ok {
+		a.Url = val
+	}
+	if val, ok := req[kind+"_active"].(bool); ok {
+		a.Active = val
+	}
+	if val, ok := req["start_at"].(string); ok {
+		t, err := parseScheduleTime(val)
+		if err != nil {
+			return err
+		}
+		a.StartAt = t
+	}
+	if val, ok := req["end_at"].(string); ok {
+		t, err := parseScheduleTime(val)
+		if err != nil {
+			return err
+		}
+		a.EndAt = t
+	}
+	return nil
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// saveAnnouncement 发布或修改紧急通知/公告栏:
+// 传入 id 时修改该条记录; 否则与同类型同通道的最近一条比较, 仅启用状态变化时沿用原记录, 内容有变化时新建一条,
+// 这样启用/停用不会产生新的 ID, 送达与已读统计也不会被拆散.
+// 停用时同类型同通道的记录全部停用; 新内容不带排期时停用之前的记录, 带排期时保留之前的记录作为排期外的回落
+func saveAnnouncement(kind string, req map[string]any) (Announcement, error) {
+	var a Announcement
+	channel, _ := req["channel"].(string)
+
+	if id, ok := req["id"].(float64); ok && id > 0 {
+		if err := db.Where("id = ? AND kind = ?", uint(id), kind).First(&a).Error; err != nil {
+			return a, err
+		}
+		if err := applyAnnouncementFields(&a, kind, req); err != nil {
+			return a, err
+		}
+		return a, db.Save(&a).Error
+	}
+
+	var latest Announcement
+	if err := db.Where("kind = ? AND channel = ?", kind, channel).Order("id desc").First(&latest).Error; err == nil {
+		a = latest
+		a.Active = true
+		if err := applyAnnouncementFields(&a, kind, req); err != nil {
+			return a, err
+		}
+		if a.Title == latest.Title && a.Content == latest.Content && a.Url == latest.Url &&
+			a.Scope == latest.Scope && a.Locales == latest.Locales && a.OS == latest.OS &&
+			sameTime(a.StartAt, latest.StartAt) && sameTime(a.EndAt, latest.EndAt) {
+			if err := db.Model(&latest).Update("active", a.Active).Error; err != nil {
+				return a, err
+			}
+			if !a.Active {
+				return a, deactivateAnnouncements(kind, channel, a.ID)
+			}
+			return a, nil
+		}
+		a.ID = 0
+		a.CreatedAt = time.Time{}
+	} else {
+		a = Announcement{Kind: kind, Channel: channel, Active: true}
+		if err := applyAnnouncementFields(&a, kind, req); err != nil {
+			return a, err
+		}
+	}
+	if err := db.Create(&a).Error; err != nil {
+		return a, err
+	}
+	if !a.Active || (a.StartAt == nil && a.EndAt == nil) {
+		return a, deactivateAnnouncements(kind, channel, a.ID)
+	}
+	return a, nil
+}
+
+// deactivateAnnouncements 停用同类型同通道除 keepID 以外的记录, 避免被替换的旧内容重新下发
+func deactivateAnnouncements(kind, channel string, keepID uint) error {
+	return db.Model(&Announcement{}).
+		Where("kind = ? AND channel = ? AND id <> ? AND active = ?", kind, channel, keepID, true).
+		Update("active", false).Error
+}
+
+// activeAnnouncement 返回当前对该客户端生效的通知: 客户端所在通道的通知优先于全局通知,
+// 同一范围内多条同时生效时以最新发布的为准, 因此带排期的公告开始前或到期后会回落到之前仍启用的那条
+func activeAnnouncement(kind string, record TelemetryRecord) (Announcement, bool) {
+	var items []Announcement
+	db.Where("kind = ? AND active = ? AND channel IN ('', ?)", kind, true, record.Channel).
+		Order("channel = '', id desc").Find(&items)
+	for _, a := range items {
+		if inSchedule(a.StartAt, a.EndAt) && targeted(a.Scope, a.Locales, a.OS, record) {
+			return a, true
+		}
+	}
+	return Announcement{}, false
+}
+
+// recordDelivery 记录公告已下发到该机器, 重复下发不会覆盖首次送达时间
+func recordDelivery(announcementID uint, machineID string) {
+	if announcementID == 0 {
+		return
+	}
+	db.Clauses(clause.OnConflict{DoNothing: true}).Create(&AnnouncementReceipt{
+		AnnouncementID: announcementID,
+		MachineID:      machineID,
+		DeliveredAt:    time.Now(),
+	})
+}
+
+func markRead(announcementID uint, machineID string) error {
+	now := time.Now()
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "announcement_id"}, {Name: "machine_id"}},
+		DoUpdates: clause.Assignments(map[string]any{"read_at": now}),
+	}).Create(&AnnouncementReceipt{
+		AnnouncementID: announcementID,
+		MachineID:      machineID,
+		DeliveredAt:    now,
+		ReadAt:         &now,
+	}).Error
+}
+
+// fillReceiptCounts 为公告列表补充送达数与已读数
+func fillReceiptCounts(items []Announcement) {
+	if len(items) == 0 {
+		return
+	}
+	ids := make([]uint, len(items))
+	for i, a := range items {
+		ids[i] = a.ID
+	}
+
+	var rows []struct {
+		AnnouncementID uint
+		Reach          int64
+		Read           int64
+	}
+	db.Model(&AnnouncementReceipt{}).
+		Select("announcement_id, count(*) as reach, count(read_at) as read").
+		Where("announcement_id IN ?", ids).
+		Group("announcement_id").
+		Scan(&rows)
+
+	for i := range items {
+		for _, row := range rows {
+			if row.AnnouncementID == items[i].ID {
+				items[i].Reach = row.Reach
+				items[i].Read = row.Read
+			}
+		}
+	}
+}
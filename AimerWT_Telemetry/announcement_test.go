@@ -0,0 +1,111 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSaveAnnouncementLifecycle(t *testing.T) {
+	setupTestDB(t)
+	record := TelemetryRecord{MachineID: "m1", Version: "3.0"}
+	expired := time.Now().Add(-time.Hour).Format(time.RFC3339)
+
+	steps := []struct {
+		name        string
+		req         map[string]any
+		wantContent string // 空字符串表示不应有生效的通知
+		wantActive  int64
+	}{
+		{name: "publish", req: map[string]any{"content": "first", "scope": "all"}, wantContent: "first", wantActive: 1},
+		{name: "edit", req: map[string]any{"content": "second", "scope": "all"}, wantContent: "second", wantActive: 1},
+		{name: "disable", req: map[string]any{"content": "second", "scope": "all", "alert_active": false}},
+		{name: "enable", req: map[string]any{"content": "second", "scope": "all", "alert_active": true}, wantContent: "second", wantActive: 1},
+		{name: "expired schedule falls back", req: map[string]any{"content": "third", "scope": "all", "end_at": expired}, wantContent: "second", wantActive: 2},
+		{name: "disable scheduled", req: map[string]any{"content": "third", "scope": "all", "end_at": expired, "alert_active": false}},
+	}
+	for _, step := range steps {
+		if _, err := saveAnnouncement("alert", step.req); err != nil {
+			t.Fatalf("%s: saveAnnouncement: %v", step.name, err)
+		}
+
+		got := ""
+		if a, ok := activeAnnouncement("alert", record); ok {
+			got = a.Content
+		}
+		if got != step.wantContent {
+			t.Errorf("%s: active content = %q, want %q", step.name, got, step.wantContent)
+		}
+
+		var active int64
+		db.Model(&Announcement{}).Where("kind = ? AND active = ?", "alert", true).Count(&active)
+		if active != step.wantActive {
+			t.Errorf("%s: %d active rows, want %d", step.name, active, step.wantActive)
+		}
+	}
+}
+
+func TestParseScheduleTime(t *testing.T) {
+	tests := []struct {
+		value   string
+		wantNil bool
+		wantErr bool
+	}{
+		{value: "", wantNil: true},
+		{value: "2026-01-02T15:04:05+08:00"},
+		{value: "2026-01-02T15:04"},
+		{value: "2026-01-02 15:04:05"},
+		{value: "2026-13-02T15:04", wantNil: true, wantErr: true},
+		{value: "tomorrow", wantNil: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseScheduleTime(tt.value)
+		if (err != nil) != tt.wantErr || (got == nil) != tt.wantNil {
+			t.Errorf("parseScheduleTime(%q) = %v, %v", tt.value, got, err)
+		}
+	}
+
+	setupTestDB(t)
+	if _, err := saveAnnouncement("notice", map[string]any{"content": "x", "end_at": "2026-02-30"}); err != errInvalidSchedule {
+		t.Errorf("saveAnnouncement with a bad end_at: err = %v, want errInvalidSchedule", err)
+	}
+	var count int64
+	db.Model(&Announcement{}).Count(&count)
+	if count != 0 {
+		t.Errorf("a rejected announcement was stored (%d rows)", count)
+	}
+}
+
+func TestActiveAnnouncementChannel(t *testing.T) {
+	setupTestDB(t)
+	future := time.Now().Add(time.Hour).Format(time.RFC3339)
+	publish := func(req map[string]any) {
+		t.Helper()
+		if _, err := saveAnnouncement("notice", req); err != nil {
+			t.Fatalf("saveAnnouncement(%v): %v", req, err)
+		}
+	}
+	publish(map[string]any{"content": "global", "scope": "all"})
+	publish(map[string]any{"content": "beta", "scope": "all", "channel": "beta"})
+	publish(map[string]any{"content": "dev later", "scope": "all", "channel": "dev", "start_at": future})
+	publish(map[string]any{"content": "zh only", "scope": "all", "channel": "zh", "locales": "zh"})
+
+	tests := []struct {
+		record TelemetryRecord
+		want   string
+	}{
+		{record: TelemetryRecord{Version: "3.0", Channel: "stable"}, want: "global"},
+		{record: TelemetryRecord{Version: "3.0", Channel: "beta"}, want: "beta"},
+		{record: TelemetryRecord{Version: "3.0", Channel: "dev"}, want: "global"},
+		{record: TelemetryRecord{Version: "3.0", Channel: "zh", Locale: "zh-CN"}, want: "zh only"},
+		{record: TelemetryRecord{Version: "3.0", Channel: "zh", Locale: "en-US"}, want: "global"},
+	}
+	for _, tt := range tests {
+		got := ""
+		if a, ok := activeAnnouncement("notice", tt.record); ok {
+			got = a.Content
+		}
+		if got != tt.want {
+			t.Errorf("channel %q locale %q: got %q, want %q", tt.record.Channel, tt.record.Locale, got, tt.want)
+		}
+	}
+}
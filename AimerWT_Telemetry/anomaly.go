@@ -0,0 +1,108 @@
+package main
+
+import (
+	"log"
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+var anomalyIntervalMinutes = envInt("TELEMETRY_ANOMALY_INTERVAL_MINUTES", 10)
+
+const (
+	anomalyWindowSize = 36  // 滚动基线保留的采样数
+	anomalyMinSamples = 6   // 基线至少需要的采样数, 不足时不告警
+	anomalySigma      = 3.0 // 偏离基线的标准差倍数
+	anomalyMinChange  = 0.5 // 同时要求相对基线变化超过 50%, 避免低流量时误报
+)
+
+// ingestCounter 统计两次采样之间 /telemetry 成功入库的上报次数
+var ingestCounter atomic.Int64
+
+type metricWindow struct {
+	samples []float64
+}
+
+func (w *metricWindow) push(v float64) {
+	w.samples = append(w.samples, v)
+	if len(w.samples) > anomalyWindowSize {
+		w.samples = w.samples[1:]
+	}
+}
+
+func (w *metricWindow) baseline() (mean, std float64) {
+	if len(w.samples) == 0 {
+		return 0, 0
+	}
+	for _, v := range w.samples {
+		mean += v
+	}
+	mean /= float64(len(w.samples))
+	for _, v := range w.samples {
+		std += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(std / float64(len(w.samples)))
+}
+
+// check 将当前值与滚动基线比较, 明显偏离时返回异常记录
+func (w *metricWindow) check(metric string, value float64) *AnomalyEvent {
+	defer w.push(value)
+	if len(w.samples) < anomalyMinSamples {
+		return nil
+	}
+	mean, std := w.baseline()
+	if mean == 0 {
+		return nil
+	}
+	diff := value - mean
+	if math.Abs(diff) <= anomalySigma*std || math.Abs(diff)/mean < anomalyMinChange {
+		return nil
+	}
+	event := &AnomalyEvent{Metric: metric, Value: value, Baseline: mean, Direction: "spike"}
+	if diff < 0 {
+		event.Direction = "drop"
+	}
+	return event
+}
+
+// anomalyMetrics 定义参与检测的指标及其采样方式
+var anomalyMetrics = map[string]func() float64{
+	"ingest_rate": func() float64 {
+		return float64(ingestCounter.Swap(0))
+	},
+	"error_logs": func() float64 {
+		return float64(errorLogCounter.Swap(0))
+	},
+	"online_users": func() float64 {
+		var count int64
+		excludeBlocked(db.Model(&TelemetryRecord{})).Where("last_seen_at > ?", onlineSince(onlineMinutes)).Count(&count)
+		return float64(count)
+	},
+}
+
+func startAnomalyDetector() {
+	if anomalyIntervalMinutes <= 0 {
+		return
+	}
+	windows := make(map[string]*metricWindow, len(anomalyMetrics))
+	for name := range anomalyMetrics {
+		windows[name] = &metricWindow{}
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(anomalyIntervalMinutes) * time.Minute)
+		defer ticker.Stop()
+		for range ticker.C {
+			for name, sample := range anomalyMetrics {
+				event := windows[name].check(name, sample())
+				if event == nil {
+					continue
+				}
+				log.Printf("检测到异常: %s 当前 %.0f, 基线 %.1f (%s)", event.Metric, event.Value, event.Baseline, event.Direction)
+				if err := db.Create(event).Error; err != nil {
+					log.Printf("写入异常记录失败: %v", err)
+				}
+			}
+		}
+	}()
+}
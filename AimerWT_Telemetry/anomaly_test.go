@@ -0,0 +1,55 @@
+package main
+
+import "testing"
+
+func TestMetricWindowCheck(t *testing.T) {
+	flat := []float64{10, 10, 10, 10, 10, 10}
+	noisy := []float64{8, 12, 8, 12, 8, 12}
+	tests := []struct {
+		name          string
+		samples       []float64
+		value         float64
+		wantDirection string // 空字符串表示不应告警
+	}{
+		{name: "not enough samples", samples: []float64{10, 10, 10, 10, 10}, value: 100},
+		{name: "zero baseline", samples: []float64{0, 0, 0, 0, 0, 0}, value: 50},
+		{name: "unchanged", samples: flat, value: 10},
+		{name: "spike", samples: flat, value: 20, wantDirection: "spike"},
+		{name: "drop", samples: flat, value: 2, wantDirection: "drop"},
+		{name: "within sigma", samples: noisy, value: 15},
+		{name: "beyond sigma", samples: noisy, value: 17, wantDirection: "spike"},
+		{name: "small relative change", samples: []float64{100, 100, 100, 100, 100, 100}, value: 120},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &metricWindow{samples: append([]float64(nil), tt.samples...)}
+			event := w.check("ingest_rate", tt.value)
+			switch {
+			case tt.wantDirection == "" && event != nil:
+				t.Errorf("got %s event, want none", event.Direction)
+			case tt.wantDirection != "" && event == nil:
+				t.Errorf("got no event, want %s", tt.wantDirection)
+			case event != nil:
+				if event.Direction != tt.wantDirection || event.Metric != "ingest_rate" || event.Value != tt.value {
+					t.Errorf("got %+v, want direction %s value %v", event, tt.wantDirection, tt.value)
+				}
+			}
+			if n := len(w.samples); n != len(tt.samples)+1 || w.samples[n-1] != tt.value {
+				t.Errorf("value was not pushed into the window: %v", w.samples)
+			}
+		})
+	}
+}
+
+func TestMetricWindowSize(t *testing.T) {
+	w := &metricWindow{}
+	for i := 0; i < anomalyWindowSize+10; i++ {
+		w.check("online_users", float64(i))
+	}
+	if len(w.samples) != anomalyWindowSize {
+		t.Fatalf("window holds %d samples, want %d", len(w.samples), anomalyWindowSize)
+	}
+	if w.samples[0] != 10 {
+		t.Errorf("oldest sample = %v, want 10", w.samples[0])
+	}
+}
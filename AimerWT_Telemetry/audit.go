@@ -0,0 +1,15 @@
+package main
+
+import (
+	"log"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recordAudit 写入一条管理端审计记录, 写入失败只记日志, 不影响操作本身
+func recordAudit(c *gin.Context, action, detail string) {
+	entry := AuditEntry{Action: action, Detail: detail, IP: c.ClientIP()}
+	if err := db.Create(&entry).Error; err != nil {
+		log.Printf("写入审计记录失败: %v", err)
+	}
+}
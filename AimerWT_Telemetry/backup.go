@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var backupDir = envString("TELEMETRY_BACKUP_DIR", "backups")
+var backupKeep = envInt("TELEMETRY_BACKUP_KEEP", 7)
+var backupIntervalHours = envInt("TELEMETRY_BACKUP_INTERVAL_HOURS", 24)
+
+var backupMu sync.Mutex
+
+// runBackup 使用 VACUUM INTO 生成一致的在线快照, 并按保留数量清理旧快照
+func runBackup() (string, error) {
+	backupMu.Lock()
+	defer backupMu.Unlock()
+
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		return "", err
+	}
+
+	name := fmt.Sprintf("telemetry_%s.db", time.Now().Format("20060102_150405"))
+	path := filepath.Join(backupDir, name)
+	if err := db.Exec("VACUUM INTO ?", path).Error; err != nil {
+		return "", err
+	}
+
+	pruneBackups()
+	return name, nil
+}
+
+func listBackups() []string {
+	entries, err := os.ReadDir(backupDir)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasPrefix(e.Name(), "telemetry_") && strings.HasSuffix(e.Name(), ".db") {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func pruneBackups() {
+	if backupKeep <= 0 {
+		return
+	}
+	names := listBackups()
+	for len(names) > backupKeep {
+		if err := os.Remove(filepath.Join(backupDir, names[0])); err != nil {
+			log.Printf("清理旧备份失败: %v", err)
+		}
+		names = names[1:]
+	}
+}
+
+func startBackupScheduler() {
+	if backupIntervalHours <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(time.Duration(backupIntervalHours) * time.Hour)
+		defer ticker.Stop()
+		for range ticker.C {
+			if name, err := runBackup(); err != nil {
+				log.Printf("定时备份失败: %v", err)
+			} else {
+				log.Printf("定时备份完成: %s", name)
+			}
+		}
+	}()
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	BlockKindIP        = "ip"
+	BlockKindMachineID = "machine_id"
+)
+
+// blockedRecordClause 排除当前仍生效的封禁 IP 与机器码, 需要传入两次当前时间
+const blockedRecordClause = `machine_id NOT IN (SELECT value FROM block_entries WHERE kind = 'machine_id' AND (expires_at IS NULL OR expires_at > ?))
+	AND COALESCE(last_ip, '') NOT IN (SELECT value FROM block_entries WHERE kind = 'ip' AND (expires_at IS NULL OR expires_at > ?))`
+
+func excludeBlocked(q *gorm.DB) *gorm.DB {
+	now := time.Now()
+	return q.Where(blockedRecordClause, now, now)
+}
+
+// blockedMachineClause 用于没有 last_ip 字段的表, IP 封禁按该机器最近一次上报的 IP 判断
+const blockedMachineClause = `machine_id NOT IN (SELECT value FROM block_entries WHERE kind = 'machine_id' AND (expires_at IS NULL OR expires_at > ?))
+	AND machine_id NOT IN (SELECT machine_id FROM telemetry_records WHERE last_ip IN (SELECT value FROM block_entries WHERE kind = 'ip' AND (expires_at IS NULL OR expires_at > ?)))`
+
+func excludeBlockedMachines(q *gorm.DB) *gorm.DB {
+	now := time.Now()
+	return q.Where(blockedMachineClause, now, now)
+}
+
+func isBlocked(kind, value string) bool {
+	if value == "" {
+		return false
+	}
+	var count int64
+	db.Model(&BlockEntry{}).
+		Where("kind = ? AND value = ?", kind, value).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+		Count(&count)
+	return count > 0
+}
+
+func addBlock(kind, value, reason string, expiresHours int) (BlockEntry, error) {
+	entry := BlockEntry{Kind: kind, Value: value, Reason: reason}
+	if expiresHours > 0 {
+		t := time.Now().Add(time.Duration(expiresHours) * time.Hour)
+		entry.ExpiresAt = &t
+	}
+	err := db.Where(BlockEntry{Kind: kind, Value: value}).
+		Assign(BlockEntry{Reason: reason, ExpiresAt: entry.ExpiresAt}).
+		FirstOrCreate(&entry).Error
+	return entry, err
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UserFilter 与看板筛选栏一致的用户筛选条件, 空字段表示不限
+type UserFilter struct {
+	OS      string `json:"os" form:"os"`
+	Arch    string `json:"arch" form:"arch"`
+	Version string `json:"version" form:"version"`
+	Locale  string `json:"locale" form:"locale"`
+	Channel string `json:"channel" form:"channel"`
+}
+
+func (f UserFilter) apply(query *gorm.DB) *gorm.DB {
+	if f.OS != "" {
+		query = query.Where("os = ?", f.OS)
+	}
+	if f.Arch != "" {
+		query = query.Where("arch = ?", f.Arch)
+	}
+	if f.Version != "" {
+		query = query.Where("version = ?", f.Version)
+	}
+	if f.Locale != "" {
+		query = query.Where("locale = ?", f.Locale)
+	}
+	if f.Channel != "" {
+		query = query.Where("channel = ?", f.Channel)
+	}
+	return query
+}
+
+// drilldownDimensions 看板下钻允许的维度: 与筛选栏字段一致, 另含系统图表使用的 os_name; date 单独处理
+var drilldownDimensions = []string{"os", "os_name", "arch", "version", "locale", "channel"}
+
+// applyDrilldown 按下钻维度追加等值条件, 维度不在白名单内时返回 false
+func applyDrilldown(query *gorm.DB, dimension, value string) (*gorm.DB, bool) {
+	if dimension == "" || value == "" {
+		return query, true
+	}
+	if dimension == "date" {
+		return query.Where("date(created_at) = ?", value), true
+	}
+	if !slices.Contains(drilldownDimensions, dimension) {
+		return query, false
+	}
+	return query.Where(dimension+" = ?", value), true
+}
+
+func saveSegment(name string, filter UserFilter) (Segment, error) {
+	raw, _ := json.Marshal(filter)
+	seg := Segment{Name: name}
+	err := db.Where(Segment{Name: name}).Assign(Segment{Filter: string(raw)}).FirstOrCreate(&seg).Error
+	return seg, err
+}
+
+func loadSegmentFilter(name string) (UserFilter, error) {
+	var seg Segment
+	var filter UserFilter
+	if err := db.Where("name = ?", name).First(&seg).Error; err != nil {
+		return filter, err
+	}
+	err := json.Unmarshal([]byte(seg.Filter), &filter)
+	return filter, err
+}
+
+const bulkTokenTTL = 5 * time.Minute
+
+type bulkPreview struct {
+	filter  UserFilter
+	command string
+	count   int64
+	expires time.Time
+}
+
+// bulkPreviews 预览生成的确认令牌, 执行时必须带回同一令牌, 且筛选条件与命令不能变化
+var bulkPreviews = struct {
+	sync.Mutex
+	entries map[string]bulkPreview
+}{entries: map[string]bulkPreview{}}
+
+func bulkTargets(filter UserFilter) *gorm.DB {
+	return filter.apply(excludeBlocked(db.Model(&TelemetryRecord{})))
+}
+
+func previewBulkCommand(filter UserFilter, command string) (string, int64) {
+	var count int64
+	bulkTargets(filter).Count(&count)
+
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	token := hex.EncodeToString(buf)
+
+	bulkPreviews.Lock()
+	defer bulkPreviews.Unlock()
+	now := time.Now()
+	for k, p := range bulkPreviews.entries {
+		if now.After(p.expires) {
+			delete(bulkPreviews.entries, k)
+		}
+	}
+	bulkPreviews.entries[token] = bulkPreview{filter: filter, command: command, count: count, expires: now.Add(bulkTokenTTL)}
+	return token, count
+}
+
+func executeBulkCommand(token string, filter UserFilter, command string) (int64, error) {
+	bulkPreviews.Lock()
+	preview, ok := bulkPreviews.entries[token]
+	delete(bulkPreviews.entries, token)
+	bulkPreviews.Unlock()
+
+	if !ok || time.Now().After(preview.expires) {
+		return 0, errors.New("confirmation token expired or invalid")
+	}
+	if preview.filter != filter || preview.command != command {
+		return 0, errors.New("filter or command changed since preview")
+	}
+
+	result := bulkTargets(filter).Update("pending_command", command)
+	if result.Error == nil {
+		log.Printf("批量下发指令: 预览 %d 台, 实际 %d 台", preview.count, result.RowsAffected)
+	}
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBulkPreviewToken(t *testing.T) {
+	setupTestDB(t)
+	db.Create(&[]TelemetryRecord{
+		{MachineID: "w1", Version: "3.0", OS: "Windows"},
+		{MachineID: "w2", Version: "3.0", OS: "Windows"},
+		{MachineID: "l1", Version: "3.0", OS: "Linux"},
+	})
+
+	filter := UserFilter{OS: "Windows"}
+	command := `{"type":"toast","message":"hi"}`
+
+	tests := []struct {
+		name      string
+		token     func(token string) string
+		filter    UserFilter
+		command   string
+		expire    bool
+		wantErr   bool
+		wantCount int64
+	}{
+		{name: "valid", filter: filter, command: command, wantCount: 2},
+		{name: "unknown token", token: func(string) string { return "deadbeef" }, filter: filter, command: command, wantErr: true},
+		{name: "filter changed", filter: UserFilter{OS: "Linux"}, command: command, wantErr: true},
+		{name: "command changed", filter: filter, command: `{"type":"popup","message":"hi"}`, wantErr: true},
+		{name: "expired", filter: filter, command: command, expire: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db.Model(&TelemetryRecord{}).Where("1 = 1").Update("pending_command", "")
+
+			token, count := previewBulkCommand(filter, command)
+			if count != 2 {
+				t.Fatalf("preview count = %d, want 2", count)
+			}
+			if tt.expire {
+				bulkPreviews.Lock()
+				p := bulkPreviews.entries[token]
+				p.expires = time.Now().Add(-time.Second)
+				bulkPreviews.entries[token] = p
+				bulkPreviews.Unlock()
+			}
+			if tt.token != nil {
+				token = tt.token(token)
+			}
+
+			affected, err := executeBulkCommand(token, tt.filter, tt.command)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, affected %d", affected)
+				}
+				var pending int64
+				db.Model(&TelemetryRecord{}).Where("pending_command != ''").Count(&pending)
+				if pending != 0 {
+					t.Errorf("%d machines received the command after a rejected execute", pending)
+				}
+				return
+			}
+			if err != nil || affected != tt.wantCount {
+				t.Fatalf("executeBulkCommand = (%d, %v), want (%d, nil)", affected, err, tt.wantCount)
+			}
+			// 令牌只能使用一次
+			if _, err := executeBulkCommand(token, tt.filter, tt.command); err == nil {
+				t.Errorf("token accepted twice")
+			}
+		})
+	}
+}
+
+func TestApplyDrilldown(t *testing.T) {
+	setupTestDB(t)
+	db.Create(&[]TelemetryRecord{
+		{MachineID: "w7", Version: "3.0", OS: "Windows", OSName: "Windows 7"},
+		{MachineID: "w11", Version: "3.1", OS: "Windows", OSName: "Windows 11"},
+		{MachineID: "l1", Version: "3.0", OS: "Linux"},
+	})
+
+	tests := []struct {
+		dimension string
+		value     string
+		wantOK    bool
+		wantCount int64
+	}{
+		{dimension: "", value: "", wantOK: true, wantCount: 3},
+		{dimension: "os", value: "Windows", wantOK: true, wantCount: 2},
+		{dimension: "os_name", value: "Windows 7", wantOK: true, wantCount: 1},
+		{dimension: "version", value: "3.0", wantOK: true, wantCount: 2},
+		{dimension: "date", value: "2000-01-01", wantOK: true, wantCount: 0},
+		{dimension: "machine_id", value: "w7", wantOK: false},
+		{dimension: "1=1 OR os", value: "x", wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dimension, func(t *testing.T) {
+			query, ok := applyDrilldown(db.Model(&TelemetryRecord{}), tt.dimension, tt.value)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			var count int64
+			query.Count(&count)
+			if count != tt.wantCount {
+				t.Errorf("count = %d, want %d", count, tt.wantCount)
+			}
+		})
+	}
+}
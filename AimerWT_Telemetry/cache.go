@@ -0,0 +1,53 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+var statsCacheTTL = time.Duration(envInt("TELEMETRY_STATS_CACHE_SECONDS", 15)) * time.Second
+
+type statsCacheEntry struct {
+	stats   StatsResponse
+	expires time.Time
+}
+
+// statsCache 按查询参数缓存 /admin/stats 的聚合结果, 避免多人查看或频繁刷新时重复执行全表 GROUP BY
+var statsCache = struct {
+	sync.Mutex
+	entries map[string]statsCacheEntry
+}{entries: map[string]statsCacheEntry{}}
+
+func getCachedStats(key string) (StatsResponse, bool) {
+	if statsCacheTTL <= 0 {
+		return StatsResponse{}, false
+	}
+	statsCache.Lock()
+	defer statsCache.Unlock()
+	entry, ok := statsCache.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		return StatsResponse{}, false
+	}
+	return entry.stats, true
+}
+
+func setCachedStats(key string, stats StatsResponse) {
+	if statsCacheTTL <= 0 {
+		return
+	}
+	statsCache.Lock()
+	defer statsCache.Unlock()
+	now := time.Now()
+	for k, entry := range statsCache.entries {
+		if now.After(entry.expires) {
+			delete(statsCache.entries, k)
+		}
+	}
+	statsCache.entries[key] = statsCacheEntry{stats: stats, expires: now.Add(statsCacheTTL)}
+}
+
+func invalidateStatsCache() {
+	statsCache.Lock()
+	statsCache.entries = map[string]statsCacheEntry{}
+	statsCache.Unlock()
+}
@@ -0,0 +1,28 @@
+package main
+
+// setChannelConfig 以写时复制方式更新通道配置, 避免与正在下发的请求共享同一个 map
+func setChannelConfig(channel string, cfg ChannelConfig) {
+	channels := make(map[string]ChannelConfig, len(sysConfig.Channels)+1)
+	for k, v := range sysConfig.Channels {
+		channels[k] = v
+	}
+	channels[channel] = cfg
+	sysConfig.Channels = channels
+}
+
+// applyChannelConfig 用客户端所在通道显式设置过的更新提示覆盖全局配置, 并处理对该通道单独关闭的公告
+func applyChannelConfig(cfg *SystemConfig, channel string) {
+	if override, ok := cfg.Channels[channel]; ok {
+		if override.UpdateSet {
+			cfg.UpdateActive = override.UpdateActive
+			cfg.UpdateContent = override.UpdateContent
+			cfg.UpdateUrl = override.UpdateUrl
+		}
+		if override.NoticeSet && !override.NoticeActive {
+			cfg.NoticeActive = false
+			cfg.NoticeID = 0
+			cfg.NoticeContent = ""
+		}
+	}
+	cfg.Channels = nil
+}
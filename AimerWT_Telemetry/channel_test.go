@@ -0,0 +1,73 @@
+package main
+
+import "testing"
+
+func TestApplyChannelConfig(t *testing.T) {
+	global := SystemConfig{
+		UpdateActive: true, UpdateContent: "global update", UpdateUrl: "https://example.com/global",
+		NoticeActive: true, NoticeID: 1, NoticeContent: "global notice",
+	}
+	tests := []struct {
+		name       string
+		channels   map[string]ChannelConfig
+		channel    string
+		wantUpdate string // 空字符串表示不应下发更新提示
+		wantNotice string // 空字符串表示不应下发公告
+	}{
+		{name: "no override", channel: "stable", wantUpdate: "global update", wantNotice: "global notice"},
+		{
+			name:       "other channel",
+			channels:   map[string]ChannelConfig{"beta": {NoticeSet: true, NoticeActive: true}},
+			channel:    "stable",
+			wantUpdate: "global update",
+			wantNotice: "global notice",
+		},
+		{
+			name:       "channel notice enabled",
+			channels:   map[string]ChannelConfig{"beta": {NoticeSet: true, NoticeActive: true}},
+			channel:    "beta",
+			wantUpdate: "global update",
+			wantNotice: "global notice",
+		},
+		{
+			name:       "channel notice turned off",
+			channels:   map[string]ChannelConfig{"beta": {NoticeSet: true, NoticeActive: false}},
+			channel:    "beta",
+			wantUpdate: "global update",
+		},
+		{
+			name:       "channel update",
+			channels:   map[string]ChannelConfig{"beta": {UpdateSet: true, UpdateActive: true, UpdateContent: "beta update"}},
+			channel:    "beta",
+			wantUpdate: "beta update",
+			wantNotice: "global notice",
+		},
+		{
+			name:       "channel update turned off",
+			channels:   map[string]ChannelConfig{"beta": {UpdateSet: true, UpdateActive: false, UpdateContent: "beta update"}},
+			channel:    "beta",
+			wantNotice: "global notice",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := global
+			cfg.Channels = tt.channels
+			applyChannelConfig(&cfg, tt.channel)
+
+			gotUpdate, gotNotice := "", ""
+			if cfg.UpdateActive {
+				gotUpdate = cfg.UpdateContent
+			}
+			if cfg.NoticeActive {
+				gotNotice = cfg.NoticeContent
+			}
+			if gotUpdate != tt.wantUpdate || gotNotice != tt.wantNotice {
+				t.Errorf("got update %q notice %q, want update %q notice %q", gotUpdate, gotNotice, tt.wantUpdate, tt.wantNotice)
+			}
+			if cfg.Channels != nil {
+				t.Errorf("channel overrides leaked to the client: %v", cfg.Channels)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+var logLevels = map[string]int{"debug": 0, "info": 1, "warn": 2, "error": 3}
+
+var logMinLevel = envString("TELEMETRY_LOG_MIN_LEVEL", "warn")
+
+const (
+	maxLogLinesPerBatch = 200
+	maxLogMessageLen    = 2000
+)
+
+// errorLogCounter 统计两次异常检测采样之间收到的 error 日志条数
+var errorLogCounter atomic.Int64
+
+type LogLine struct {
+	Level   string `json:"level"`
+	Message string `json:"message"`
+	Time    string `json:"time"`
+}
+
+type LogBatch struct {
+	MachineID string    `json:"machine_id"`
+	Version   string    `json:"version"`
+	Lines     []LogLine `json:"lines"`
+}
+
+// validate 使用与遥测上报相同的规则校验机器 ID 与版本号
+func (b LogBatch) validate() error {
+	if !machineIDPattern.MatchString(b.MachineID) {
+		return errors.New("invalid machine_id")
+	}
+	if !versionPattern.MatchString(b.Version) {
+		return errors.New("invalid version")
+	}
+	return nil
+}
+
+// acceptedLogs 按服务端最低级别过滤日志行, 截断超长消息, 返回待入库的记录
+func (b LogBatch) acceptedLogs() []ClientLog {
+	minLevel := logLevels[logMinLevel]
+	var logs []ClientLog
+	for i, line := range b.Lines {
+		if i >= maxLogLinesPerBatch {
+			break
+		}
+		level := strings.ToLower(line.Level)
+		if level == "warning" {
+			level = "warn"
+		}
+		rank, ok := logLevels[level]
+		if !ok || rank < minLevel {
+			continue
+		}
+
+		message := line.Message
+		if utf8.RuneCountInString(message) > maxLogMessageLen {
+			message = string([]rune(message)[:maxLogMessageLen])
+		}
+		loggedAt, err := time.Parse(time.RFC3339, line.Time)
+		if err != nil {
+			loggedAt = time.Now()
+		}
+		if level == "error" {
+			errorLogCounter.Add(1)
+		}
+		logs = append(logs, ClientLog{
+			MachineID: b.MachineID,
+			Version:   b.Version,
+			Level:     level,
+			Message:   message,
+			LoggedAt:  loggedAt,
+		})
+	}
+	return logs
+}
+
+// logStatsByVersion 统计各版本的错误/警告数量以及出现错误的机器占该版本用户的比例
+func logStatsByVersion(days int) []map[string]any {
+	var results []map[string]any
+	now := time.Now()
+	db.Raw(`
+		SELECT
+			l.version as version,
+			sum(case when l.level = 'error' then 1 else 0 end) as errors,
+			sum(case when l.level = 'warn' then 1 else 0 end) as warnings,
+			count(distinct case when l.level = 'error' then l.machine_id end) as error_machines,
+			(SELECT count(*) FROM telemetry_records r WHERE r.version = l.version AND r.deleted_at IS NULL AND `+blockedRecordClause+`) as users
+		FROM client_logs l
+		WHERE l.created_at > ? AND `+blockedMachineClause+`
+		GROUP BY l.version
+		ORDER BY errors DESC
+	`, now, now, now.AddDate(0, 0, -days), now, now).Scan(&results)
+
+	for _, row := range results {
+		users, _ := row["users"].(int64)
+		machines, _ := row["error_machines"].(int64)
+		rate := 0.0
+		if users > 0 {
+			rate = float64(machines) / float64(users)
+		}
+		row["error_rate"] = rate
+	}
+	return results
+}
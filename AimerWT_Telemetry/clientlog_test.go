@@ -0,0 +1,53 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestLogBatchValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		batch   LogBatch
+		wantErr bool
+	}{
+		{name: "valid", batch: LogBatch{MachineID: "abc-123", Version: "3.1.0"}},
+		{name: "missing machine_id", batch: LogBatch{Version: "3.1.0"}, wantErr: true},
+		{name: "bad machine_id", batch: LogBatch{MachineID: "a b", Version: "3.1.0"}, wantErr: true},
+		{name: "missing version", batch: LogBatch{MachineID: "abc"}, wantErr: true},
+		{name: "bad version", batch: LogBatch{MachineID: "abc", Version: "<script>"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.batch.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAcceptedLogs(t *testing.T) {
+	prev := logMinLevel
+	logMinLevel = "warn"
+	t.Cleanup(func() { logMinLevel = prev })
+
+	batch := LogBatch{MachineID: "m1", Version: "3.0", Lines: []LogLine{
+		{Level: "info", Message: "dropped"},
+		{Level: "WARNING", Message: "kept"},
+		{Level: "error", Message: strings.Repeat("错", maxLogMessageLen+10)},
+		{Level: "fatal", Message: "unknown level"},
+	}}
+
+	logs := batch.acceptedLogs()
+	if len(logs) != 2 {
+		t.Fatalf("accepted %d lines, want 2", len(logs))
+	}
+	if logs[0].Level != "warn" {
+		t.Errorf("level = %q, want warn", logs[0].Level)
+	}
+	msg := logs[1].Message
+	if !utf8.ValidString(msg) || utf8.RuneCountInString(msg) != maxLogMessageLen {
+		t.Errorf("long message truncated to %d runes (valid UTF-8: %v), want %d", utf8.RuneCountInString(msg), utf8.ValidString(msg), maxLogMessageLen)
+	}
+}
@@ -0,0 +1,330 @@
+// telemetryctl 是遥测后端的命令行管理工具, 通过管理接口完成常用运维操作, 便于在服务器上通过 SSH 使用.
+//
+// 认证信息读取 TELEMETRY_ADMIN_USER / TELEMETRY_ADMIN_PASS, 服务地址默认 http://127.0.0.1:8080, 可用 TELEMETRY_SERVER 覆盖.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+var server = envOr("TELEMETRY_SERVER", "http://127.0.0.1:8080")
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `用法: telemetryctl <命令> [参数]
+
+命令:
+  users        列出用户              [-limit N] [-offset N]
+  export       导出 CSV              [-entity users|sessions|logs|feedback|...] [-start YYYY-MM-DD] [-end YYYY-MM-DD] [-o 文件]
+  maintenance  设置维护模式          -on|-off [-msg 文本] [-stop-new-data]
+  announce     发布通知/公告/更新    -kind alert|notice|update -content 文本 [-title 标题] [-url 地址] [-scope all] [-off]
+  purge        清理长期未活跃的记录  -days N [-no-backup]
+  backup       立即备份数据库
+  command      向筛选出的用户批量下发指令  -cmd JSON | -template 名称 -param k=v [-segment 名称] [-os] [-arch] [-version] [-locale] [-channel] [-yes]`)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "users":
+		err = cmdUsers(os.Args[2:])
+	case "export":
+		err = cmdExport(os.Args[2:])
+	case "maintenance":
+		err = cmdMaintenance(os.Args[2:])
+	case "announce":
+		err = cmdAnnounce(os.Args[2:])
+	case "purge":
+		err = cmdPurge(os.Args[2:])
+	case "backup":
+		err = cmdBackup()
+	case "command":
+		err = cmdBulkCommand(os.Args[2:])
+	default:
+		usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "错误:", err)
+		os.Exit(1)
+	}
+}
+
+func request(method, path string, body any) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, server+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(os.Getenv("TELEMETRY_ADMIN_USER"), os.Getenv("TELEMETRY_ADMIN_PASS"))
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := &http.Client{Timeout: 5 * time.Minute}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s %s 返回 %d: %s", method, path, resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	return resp, nil
+}
+
+func call(method, path string, body any, out any) error {
+	resp, err := request(method, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func cmdUsers(args []string) error {
+	fs := flag.NewFlagSet("users", flag.ExitOnError)
+	limit := fs.Int("limit", 50, "返回条数")
+	offset := fs.Int("offset", 0, "偏移量")
+	fs.Parse(args)
+
+	var resp struct {
+		Total int64 `json:"total"`
+		Items []struct {
+			MachineID  string    `json:"machine_id"`
+			Alias      string    `json:"alias"`
+			Version    string    `json:"version"`
+			OS         string    `json:"os"`
+			Locale     string    `json:"locale"`
+			LastSeenAt time.Time `json:"last_seen_at"`
+		} `json:"items"`
+	}
+	if err := call("GET", fmt.Sprintf("/admin/users?limit=%d&offset=%d", *limit, *offset), nil, &resp); err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MACHINE ID\tALIAS\tVERSION\tOS\tLOCALE\tLAST SEEN")
+	for _, u := range resp.Items {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", u.MachineID, u.Alias, u.Version, u.OS, u.Locale, u.LastSeenAt.Local().Format("2006-01-02 15:04:05"))
+	}
+	w.Flush()
+	fmt.Printf("共 %d 条, 已显示 %d 条\n", resp.Total, len(resp.Items))
+	return nil
+}
+
+func cmdExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	entity := fs.String("entity", "users", "导出的列表")
+	start := fs.String("start", "", "起始日期")
+	end := fs.String("end", "", "结束日期")
+	out := fs.String("o", "telemetry_export.csv", "输出文件, - 表示标准输出")
+	fs.Parse(args)
+
+	params := url.Values{}
+	params.Set("entity", *entity)
+	if *start != "" {
+		params.Set("start_date", *start)
+	}
+	if *end != "" {
+		params.Set("end_date", *end)
+	}
+	resp, err := request("GET", "/admin/export?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if *out == "-" {
+		_, err = io.Copy(os.Stdout, resp.Body)
+		return err
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		return err
+	}
+	fmt.Println("已导出到", *out)
+	return nil
+}
+
+func cmdMaintenance(args []string) error {
+	fs := flag.NewFlagSet("maintenance", flag.ExitOnError)
+	on := fs.Bool("on", false, "开启维护模式")
+	off := fs.Bool("off", false, "关闭维护模式")
+	msg := fs.String("msg", "", "维护公告")
+	stop := fs.Bool("stop-new-data", false, "维护期间拒绝新数据")
+	fs.Parse(args)
+
+	if *on == *off {
+		return fmt.Errorf("请指定 -on 或 -off")
+	}
+	var resp map[string]any
+	return printResult(call("POST", "/admin/control", map[string]any{
+		"action":          "maintenance",
+		"maintenance":     *on,
+		"maintenance_msg": *msg,
+		"stop_new_data":   *on && *stop,
+	}, &resp), resp)
+}
+
+func cmdAnnounce(args []string) error {
+	fs := flag.NewFlagSet("announce", flag.ExitOnError)
+	kind := fs.String("kind", "notice", "alert | notice | update")
+	title := fs.String("title", "", "通知标题 (仅 alert)")
+	content := fs.String("content", "", "内容")
+	link := fs.String("url", "", "下载地址 (仅 update)")
+	scope := fs.String("scope", "all", "推送范围 (版本号或 all)")
+	disable := fs.Bool("off", false, "停止推送")
+	fs.Parse(args)
+
+	payload := map[string]any{"action": *kind}
+	// 停止推送时只修改启用状态, 服务端会沿用当前这条公告
+	if !*disable {
+		payload["title"] = *title
+		payload["content"] = *content
+		payload["scope"] = *scope
+	}
+	switch *kind {
+	case "alert", "notice":
+		payload[*kind+"_active"] = !*disable
+	case "update":
+		if !*disable {
+			payload["url"] = *link
+		}
+		payload["update_active"] = !*disable
+	default:
+		return fmt.Errorf("未知的公告类型: %s", *kind)
+	}
+
+	var resp map[string]any
+	return printResult(call("POST", "/admin/control", payload, &resp), resp)
+}
+
+func cmdPurge(args []string) error {
+	fs := flag.NewFlagSet("purge", flag.ExitOnError)
+	days := fs.Int("days", 0, "删除超过 N 天未活跃的记录")
+	noBackup := fs.Bool("no-backup", false, "清理前不自动备份")
+	fs.Parse(args)
+
+	if *days <= 0 {
+		return fmt.Errorf("请通过 -days 指定天数")
+	}
+	if !*noBackup {
+		if err := cmdBackup(); err != nil {
+			return fmt.Errorf("清理前备份失败: %w", err)
+		}
+	}
+
+	var resp map[string]any
+	return printResult(call("POST", "/admin/purge", map[string]any{"days": *days}, &resp), resp)
+}
+
+func cmdBackup() error {
+	var resp map[string]any
+	return printResult(call("POST", "/admin/backup", nil, &resp), resp)
+}
+
+func cmdBulkCommand(args []string) error {
+	fs := flag.NewFlagSet("command", flag.ExitOnError)
+	command := fs.String("cmd", "", "下发的指令 (JSON 字符串)")
+	template := fs.String("template", "", "使用预置指令模板, 例如 popup / toast")
+	params := map[string]string{}
+	fs.Func("param", "模板参数 key=value, 可重复", func(v string) error {
+		key, value, ok := strings.Cut(v, "=")
+		if !ok {
+			return fmt.Errorf("参数格式应为 key=value")
+		}
+		params[key] = value
+		return nil
+	})
+	segment := fs.String("segment", "", "已保存的分群名称")
+	osName := fs.String("os", "", "按系统筛选")
+	arch := fs.String("arch", "", "按架构筛选")
+	version := fs.String("version", "", "按版本筛选")
+	locale := fs.String("locale", "", "按区域筛选")
+	channel := fs.String("channel", "", "按更新通道筛选")
+	yes := fs.Bool("yes", false, "跳过确认直接下发")
+	fs.Parse(args)
+
+	if *command == "" && *template == "" {
+		return fmt.Errorf("请通过 -cmd 或 -template 指定指令")
+	}
+	payload := map[string]any{
+		"segment":  *segment,
+		"command":  *command,
+		"template": *template,
+		"params":   params,
+		"filter": map[string]string{
+			"os": *osName, "arch": *arch, "version": *version, "locale": *locale, "channel": *channel,
+		},
+	}
+
+	var preview struct {
+		Count int64  `json:"count"`
+		Token string `json:"token"`
+	}
+	if err := call("POST", "/admin/bulk-command", payload, &preview); err != nil {
+		return err
+	}
+	if preview.Count == 0 {
+		fmt.Println("没有匹配的用户")
+		return nil
+	}
+	if !*yes {
+		fmt.Printf("将向 %d 台设备下发指令, 确认? [y/N] ", preview.Count)
+		var answer string
+		fmt.Scanln(&answer)
+		if answer != "y" && answer != "Y" {
+			fmt.Println("已取消")
+			return nil
+		}
+	}
+
+	payload["token"] = preview.Token
+	var resp map[string]any
+	return printResult(call("POST", "/admin/bulk-command", payload, &resp), resp)
+}
+
+func printResult(err error, resp map[string]any) error {
+	if err != nil {
+		return err
+	}
+	data, _ := json.MarshalIndent(resp, "", "  ")
+	fmt.Println(string(data))
+	return nil
+}
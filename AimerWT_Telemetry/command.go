@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type CommandParam struct {
+	Name     string `json:"name"`
+	Label    string `json:"label"`
+	MaxLen   int    `json:"max_len"`
+	Required bool   `json:"required"`
+}
+
+// CommandTemplate 预置的安全指令, 运维只需填写参数, 不再手写原始 JSON
+type CommandTemplate struct {
+	Name        string         `json:"name"`
+	Label       string         `json:"label"`
+	Description string         `json:"description"`
+	Type        string         `json:"type"`
+	Params      []CommandParam `json:"params"`
+}
+
+// commandTemplates 与客户端 on_user_command 支持的指令类型保持一致
+var commandTemplates = []CommandTemplate{
+	{
+		Name:        "popup",
+		Label:       "弹窗通知",
+		Description: "在客户端弹出模态通知框",
+		Type:        "popup",
+		Params:      []CommandParam{{Name: "message", Label: "通知内容", MaxLen: 1000, Required: true}},
+	},
+	{
+		Name:        "toast",
+		Label:       "提示消息",
+		Description: "在客户端右上角显示 5 秒的管理员消息",
+		Type:        "toast",
+		Params:      []CommandParam{{Name: "message", Label: "提示内容", MaxLen: 200, Required: true}},
+	},
+}
+
+func findCommandTemplate(name string) (CommandTemplate, bool) {
+	for _, t := range commandTemplates {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return CommandTemplate{}, false
+}
+
+// renderCommand 校验参数并生成下发给客户端的指令 JSON, 模板未声明的参数会被忽略
+func renderCommand(name string, params map[string]string) (string, error) {
+	tmpl, ok := findCommandTemplate(name)
+	if !ok {
+		return "", fmt.Errorf("unknown template: %s", name)
+	}
+	cmd := map[string]string{"type": tmpl.Type}
+	for _, p := range tmpl.Params {
+		value := strings.TrimSpace(params[p.Name])
+		if value == "" && p.Required {
+			return "", fmt.Errorf("missing param: %s", p.Name)
+		}
+		if p.MaxLen > 0 && utf8.RuneCountInString(value) > p.MaxLen {
+			return "", fmt.Errorf("param too long: %s", p.Name)
+		}
+		cmd[p.Name] = value
+	}
+	data, err := json.Marshal(cmd)
+	return string(data), err
+}
+
+// resolveCommand 优先使用模板生成指令, 未指定模板时沿用原始 JSON 字符串
+func resolveCommand(template string, params map[string]string, raw string) (string, error) {
+	if template != "" {
+		return renderCommand(template, params)
+	}
+	if raw == "" {
+		return "", errors.New("missing command")
+	}
+	return raw, nil
+}
+
+const (
+	CommandStatusDelivered = "delivered"
+	CommandStatusExecuted  = "executed"
+	CommandStatusFailed    = "failed"
+)
+
+// recordCommandDelivery 在指令随心跳响应下发时登记回执, 返回的 ID 由客户端执行后回传
+func recordCommandDelivery(record TelemetryRecord, command string) uint {
+	var parsed struct {
+		Type string `json:"type"`
+	}
+	if json.Unmarshal([]byte(command), &parsed) != nil || parsed.Type == "" {
+		parsed.Type = "unknown"
+	}
+	receipt := CommandReceipt{
+		MachineID:   record.MachineID,
+		Type:        parsed.Type,
+		Version:     record.Version,
+		Status:      CommandStatusDelivered,
+		DeliveredAt: time.Now(),
+	}
+	if err := db.Create(&receipt).Error; err != nil {
+		log.Printf("记录指令下发失败: %v", err)
+		return 0
+	}
+	return receipt.ID
+}
+
+// ackCommand 记录客户端执行结果, 每条回执只接受一次确认
+func ackCommand(id uint, machineID, status, errMsg string) error {
+	if utf8.RuneCountInString(errMsg) > 500 {
+		errMsg = string([]rune(errMsg)[:500])
+	}
+	now := time.Now()
+	return db.Model(&CommandReceipt{}).
+		Where("id = ? AND machine_id = ? AND status = ?", id, machineID, CommandStatusDelivered).
+		Updates(map[string]any{"status": status, "error": errMsg, "acked_at": now}).Error
+}
+
+type CommandOutcomeStats struct {
+	Pending int64            `json:"pending"`
+	Items   []map[string]any `json:"items"`
+}
+
+// commandOutcomeStats 按指令类型与客户端版本汇总下发、执行成功与失败数量
+func commandOutcomeStats(days int) CommandOutcomeStats {
+	stats := CommandOutcomeStats{Items: []map[string]any{}}
+	excludeBlocked(db.Model(&TelemetryRecord{})).Where("pending_command <> ''").Count(&stats.Pending)
+
+	db.Model(&CommandReceipt{}).
+		Select(`type, version, count(*) as delivered,
+			sum(case when status = ? then 1 else 0 end) as executed,
+			sum(case when status = ? then 1 else 0 end) as failed,
+			max(delivered_at) as last_at`, CommandStatusExecuted, CommandStatusFailed).
+		Where("delivered_at > ?", time.Now().AddDate(0, 0, -days)).
+		Group("type, version").Order("delivered desc").
+		Scan(&stats.Items)
+	return stats
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		params   map[string]string
+		want     string
+		wantErr  string
+	}{
+		{name: "popup", template: "popup", params: map[string]string{"message": "hello"}, want: `{"message":"hello","type":"popup"}`},
+		{name: "toast trims", template: "toast", params: map[string]string{"message": "  hi  "}, want: `{"message":"hi","type":"toast"}`},
+		{name: "escapes json", template: "popup", params: map[string]string{"message": `say "hi"`}, want: `{"message":"say \"hi\"","type":"popup"}`},
+		{name: "ignores undeclared params", template: "toast", params: map[string]string{"message": "x", "type": "exec"}, want: `{"message":"x","type":"toast"}`},
+		{name: "max length in runes", template: "toast", params: map[string]string{"message": strings.Repeat("中", 200)}, want: `{"message":"` + strings.Repeat("中", 200) + `","type":"toast"}`},
+		{name: "too long", template: "toast", params: map[string]string{"message": strings.Repeat("中", 201)}, wantErr: "param too long: message"},
+		{name: "missing", template: "popup", params: map[string]string{}, wantErr: "missing param: message"},
+		{name: "blank", template: "popup", params: map[string]string{"message": "   "}, wantErr: "missing param: message"},
+		{name: "unknown template", template: "exec", params: map[string]string{"message": "x"}, wantErr: "unknown template: exec"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := renderCommand(tt.template, tt.params)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
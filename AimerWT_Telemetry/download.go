@@ -0,0 +1,64 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/url"
+	"regexp"
+	"time"
+)
+
+var downloadNamePattern = regexp.MustCompile(`^[a-zA-Z0-9._-]{1,64}$`)
+
+// saveDownloadLink 登记或更新一个托管下载短链, 更新提示中的下载地址可以填写 /download/<name> 以便统计下载量
+func saveDownloadLink(name, version, target string) (DownloadLink, error) {
+	if !downloadNamePattern.MatchString(name) {
+		return DownloadLink{}, errors.New("invalid name")
+	}
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return DownloadLink{}, errors.New("invalid url")
+	}
+	link := DownloadLink{Name: name}
+	err = db.Where(DownloadLink{Name: name}).Assign(DownloadLink{Version: version, Url: target}).FirstOrCreate(&link).Error
+	return link, err
+}
+
+// recordDownload 记录一次下载, IP 只保存哈希用于去重
+func recordDownload(link DownloadLink, ip, machineID string) {
+	sum := sha256.Sum256([]byte(ip))
+	event := DownloadEvent{
+		LinkName:  link.Name,
+		Version:   link.Version,
+		MachineID: machineID,
+		IPHash:    hex.EncodeToString(sum[:8]),
+	}
+	if err := db.Create(&event).Error; err != nil {
+		log.Printf("记录下载失败: %v", err)
+	}
+}
+
+type DownloadStats struct {
+	Files []map[string]any `json:"files"`
+	Daily []map[string]any `json:"daily"`
+}
+
+func downloadStats(days int) DownloadStats {
+	var stats DownloadStats
+	since := time.Now().AddDate(0, 0, -days)
+
+	db.Model(&DownloadEvent{}).
+		Select("link_name as name, version, count(*) as downloads, count(distinct ip_hash) as unique_ips, max(created_at) as last_at").
+		Where("created_at > ?", since).
+		Group("link_name, version").Order("downloads desc").
+		Scan(&stats.Files)
+
+	db.Model(&DownloadEvent{}).
+		Select("date(created_at) as date, version, count(*) as downloads").
+		Where("created_at > ?", since).
+		Group("date, version").Order("date asc").
+		Scan(&stats.Daily)
+	return stats
+}
@@ -0,0 +1,65 @@
+package main
+
+import "strings"
+
+const (
+	ExperimentEventExposure   = "exposure"
+	ExperimentEventConversion = "conversion"
+)
+
+func (e Experiment) variantList() []string {
+	var variants []string
+	for _, v := range strings.Split(e.Variants, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			variants = append(variants, v)
+		}
+	}
+	return variants
+}
+
+// assign 按机器码确定性地分配实验分组, 未进入实验流量时返回空字符串
+func (e Experiment) assign(machineID string) string {
+	variants := e.variantList()
+	if !e.Active || len(variants) == 0 || bucketOf("exp:"+e.Name, machineID) >= e.Percentage {
+		return ""
+	}
+	return variants[bucketOf("variant:"+e.Name, machineID)%len(variants)]
+}
+
+// assignExperiments 返回该机器参与的全部实验及分组
+func assignExperiments(machineID string) map[string]string {
+	var experiments []Experiment
+	db.Where("active = ?", true).Find(&experiments)
+
+	result := make(map[string]string, len(experiments))
+	for _, e := range experiments {
+		if variant := e.assign(machineID); variant != "" {
+			result[e.Name] = variant
+		}
+	}
+	return result
+}
+
+// experimentResults 按分组统计曝光与转化的去重机器数
+func experimentResults(name string) []map[string]any {
+	var results []map[string]any
+	db.Model(&ExperimentEvent{}).
+		Select(`variant,
+			count(distinct case when kind = 'exposure' then machine_id end) as exposures,
+			count(distinct case when kind = 'conversion' then machine_id end) as conversions`).
+		Where("experiment = ?", name).
+		Group("variant").
+		Order("variant asc").
+		Scan(&results)
+
+	for _, row := range results {
+		exposures, _ := row["exposures"].(int64)
+		conversions, _ := row["conversions"].(int64)
+		rate := 0.0
+		if exposures > 0 {
+			rate = float64(conversions) / float64(exposures)
+		}
+		row["conversion_rate"] = rate
+	}
+	return results
+}
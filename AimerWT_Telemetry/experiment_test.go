@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestBucketOf(t *testing.T) {
+	counts := make([]int, 100)
+	for i := 0; i < 10000; i++ {
+		id := fmt.Sprintf("machine-%d", i)
+		b := bucketOf("seed", id)
+		if b < 0 || b >= 100 {
+			t.Fatalf("bucketOf(%q) = %d, out of range", id, b)
+		}
+		if again := bucketOf("seed", id); again != b {
+			t.Fatalf("bucketOf(%q) not stable: %d then %d", id, b, again)
+		}
+		counts[b]++
+	}
+	// 每桶期望 100 个, 分布明显不均说明哈希取值有误
+	for b, n := range counts {
+		if n < 50 || n > 150 {
+			t.Errorf("bucket %d has %d machines, want about 100", b, n)
+		}
+	}
+}
+
+func TestExperimentAssign(t *testing.T) {
+	tests := []struct {
+		name       string
+		exp        Experiment
+		wantInExp  int // 1000 台机器中预期进入实验的大致数量
+		wantNoneIn bool
+	}{
+		{name: "inactive", exp: Experiment{Name: "a", Active: false, Percentage: 100, Variants: "x,y"}, wantNoneIn: true},
+		{name: "no variants", exp: Experiment{Name: "a", Active: true, Percentage: 100, Variants: " , "}, wantNoneIn: true},
+		{name: "zero percent", exp: Experiment{Name: "a", Active: true, Percentage: 0, Variants: "x,y"}, wantNoneIn: true},
+		{name: "full rollout", exp: Experiment{Name: "a", Active: true, Percentage: 100, Variants: "x, y"}, wantInExp: 1000},
+		{name: "half rollout", exp: Experiment{Name: "b", Active: true, Percentage: 50, Variants: "x,y,z"}, wantInExp: 500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			variants := map[string]bool{}
+			for _, v := range tt.exp.variantList() {
+				variants[v] = true
+			}
+			seen := map[string]int{}
+			in := 0
+			for i := 0; i < 1000; i++ {
+				id := fmt.Sprintf("machine-%d", i)
+				got := tt.exp.assign(id)
+				if again := tt.exp.assign(id); again != got {
+					t.Fatalf("assign(%q) not stable: %q then %q", id, got, again)
+				}
+				if got == "" {
+					continue
+				}
+				if !variants[got] {
+					t.Fatalf("assign(%q) = %q, not a configured variant", id, got)
+				}
+				in++
+				seen[got]++
+			}
+			if tt.wantNoneIn {
+				if in != 0 {
+					t.Errorf("%d machines assigned, want none", in)
+				}
+				return
+			}
+			if in < tt.wantInExp*8/10 || in > tt.wantInExp*12/10 {
+				t.Errorf("%d machines assigned, want about %d", in, tt.wantInExp)
+			}
+			if len(seen) != len(variants) {
+				t.Errorf("variants used %v, want all of %v", seen, tt.exp.variantList())
+			}
+		})
+	}
+}
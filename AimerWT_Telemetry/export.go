@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// exportEntity 描述一种可导出的列表: 导出的列及对应的表头、日期范围作用的列, 以及允许按等值筛选的查询参数
+type exportEntity struct {
+	model      any
+	columns    []string
+	headers    []string
+	dateColumn string
+	filters    []string
+	scope      func(c *gin.Context, q *gorm.DB) *gorm.DB
+}
+
+// excludeBlockedScope 用于带 machine_id 的明细表, 排除已封禁机器的数据
+func excludeBlockedScope(c *gin.Context, q *gorm.DB) *gorm.DB {
+	return excludeBlockedMachines(q)
+}
+
+var exportEntities = map[string]exportEntity{
+	"users": {
+		model: &TelemetryRecord{},
+		columns: []string{"machine_id", "alias", "version", "channel", "os", "os_name", "os_version", "arch",
+			"python_version", "game_version", "locale", "screen_res", "created_at", "last_seen_at"},
+		headers: []string{"Machine ID", "Alias", "Version", "Channel", "OS", "OS Name", "OS Version", "Arch",
+			"Python", "Game Version", "Locale", "Screen", "First Seen", "Last Seen"},
+		dateColumn: "created_at",
+		scope: func(c *gin.Context, q *gorm.DB) *gorm.DB {
+			var filter UserFilter
+			c.ShouldBindQuery(&filter)
+			return filter.apply(excludeBlocked(q))
+		},
+	},
+	"sessions": {
+		model:      &SessionRecord{},
+		columns:    []string{"machine_id", "session_id", "version", "started_at", "last_seen_at", "ended_at"},
+		headers:    []string{"Machine ID", "Session", "Version", "Started", "Last Seen", "Ended"},
+		dateColumn: "started_at",
+		filters:    []string{"machine_id", "version"},
+		scope:      excludeBlockedScope,
+	},
+	"logs": {
+		model:      &ClientLog{},
+		columns:    []string{"machine_id", "version", "level", "message", "logged_at", "created_at"},
+		headers:    []string{"Machine ID", "Version", "Level", "Message", "Logged At", "Received At"},
+		dateColumn: "created_at",
+		filters:    []string{"machine_id", "version", "level"},
+		scope:      excludeBlockedScope,
+	},
+	"feedback": {
+		model:      &Feedback{},
+		columns:    []string{"id", "machine_id", "version", "category", "status", "message", "has_diagnostics", "created_at"},
+		headers:    []string{"ID", "Machine ID", "Version", "Category", "Status", "Message", "Diagnostics", "Created At"},
+		dateColumn: "created_at",
+		filters:    []string{"machine_id", "version", "category", "status"},
+		scope:      excludeBlockedScope,
+	},
+	"announcements": {
+		model:      &Announcement{},
+		columns:    []string{"id", "kind", "active", "title", "content", "url", "scope", "locales", "os", "channel", "start_at", "end_at", "created_at"},
+		headers:    []string{"ID", "Kind", "Active", "Title", "Content", "URL", "Scope", "Locales", "OS", "Channel", "Start", "End", "Created At"},
+		dateColumn: "created_at",
+		filters:    []string{"kind", "channel"},
+	},
+	"experiment-events": {
+		model:      &ExperimentEvent{},
+		columns:    []string{"experiment", "variant", "machine_id", "kind", "goal", "created_at"},
+		headers:    []string{"Experiment", "Variant", "Machine ID", "Kind", "Goal", "Created At"},
+		dateColumn: "created_at",
+		filters:    []string{"experiment", "variant", "kind"},
+		scope:      excludeBlockedScope,
+	},
+	"anomalies": {
+		model:      &AnomalyEvent{},
+		columns:    []string{"metric", "value", "baseline", "direction", "acknowledged", "created_at"},
+		headers:    []string{"Metric", "Value", "Baseline", "Direction", "Acknowledged", "Created At"},
+		dateColumn: "created_at",
+		filters:    []string{"metric", "direction"},
+	},
+	"commands": {
+		model:      &CommandReceipt{},
+		columns:    []string{"id", "machine_id", "type", "version", "status", "error", "delivered_at", "acked_at"},
+		headers:    []string{"ID", "Machine ID", "Type", "Version", "Status", "Error", "Delivered At", "Acked At"},
+		dateColumn: "delivered_at",
+		filters:    []string{"machine_id", "type", "version", "status"},
+		scope:      excludeBlockedScope,
+	},
+	"downloads": {
+		model:      &DownloadEvent{},
+		columns:    []string{"link_name", "version", "machine_id", "created_at"},
+		headers:    []string{"Link", "Version", "Machine ID", "Created At"},
+		dateColumn: "created_at",
+		filters:    []string{"link_name", "version"},
+		scope:      excludeBlockedScope,
+	},
+	"audit": {
+		model:      &AuditEntry{},
+		columns:    []string{"id", "action", "detail", "ip", "created_at"},
+		headers:    []string{"ID", "Action", "Detail", "IP", "Created At"},
+		dateColumn: "created_at",
+		filters:    []string{"action"},
+	},
+}
+
+// exportQuery 按实体定义组装查询, 筛选值全部通过参数绑定传入
+func (e exportEntity) exportQuery(c *gin.Context) *gorm.DB {
+	q := db.Model(e.model)
+	if e.scope != nil {
+		q = e.scope(c, q)
+	}
+	for _, name := range e.filters {
+		if value := c.Query(name); value != "" {
+			q = q.Where(name+" = ?", value)
+		}
+	}
+	if start := c.Query("start_date"); start != "" {
+		q = q.Where("date("+e.dateColumn+") >= ?", start)
+	}
+	if end := c.Query("end_date"); end != "" {
+		q = q.Where("date("+e.dateColumn+") <= ?", end)
+	}
+	return q.Select(e.columns).Order(e.dateColumn + " asc")
+}
+
+func serveExport(c *gin.Context, name string, e exportEntity) {
+	recordAudit(c, "export", name+" "+c.Request.URL.RawQuery)
+	c.Header("Content-Type", "text/csv")
+	c.Header("Content-Disposition", "attachment;filename="+name+"_export.csv")
+	if err := writeExportCSV(c.Writer, e, e.exportQuery(c)); err != nil {
+		log.Printf("导出 %s 失败: %v", name, err)
+	}
+}
+
+func writeExportCSV(w io.Writer, e exportEntity, q *gorm.DB) error {
+	rows, err := q.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	w.Write([]byte("\xEF\xBB\xBF"))
+	writer := csv.NewWriter(w)
+	writer.Write(e.headers)
+
+	values := make([]any, len(e.columns))
+	ptrs := make([]any, len(e.columns))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	record := make([]string, len(e.columns))
+	for n := 1; rows.Next(); n++ {
+		if err := rows.Scan(ptrs...); err != nil {
+			return err
+		}
+		for i, v := range values {
+			record[i] = formatExportValue(v)
+		}
+		writer.Write(record)
+		if n%1000 == 0 {
+			writer.Flush()
+		}
+	}
+	writer.Flush()
+	return rows.Err()
+}
+
+func formatExportValue(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return val.Local().Format("2006-01-02 15:04:05")
+	case []byte:
+		return string(val)
+	default:
+		return fmt.Sprint(val)
+	}
+}
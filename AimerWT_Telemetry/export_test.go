@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/csv"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestExportQueryFilters(t *testing.T) {
+	setupTestDB(t)
+	gin.SetMode(gin.TestMode)
+
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 12, 0, 0, 0, time.Local) }
+	db.Create(&[]ClientLog{
+		{MachineID: "m1", Version: "3.0", Level: "ERROR", Message: "a", CreatedAt: day(1)},
+		{MachineID: "m1", Version: "3.1", Level: "WARN", Message: "b", CreatedAt: day(2)},
+		{MachineID: "m2", Version: "3.1", Level: "ERROR", Message: "c", CreatedAt: day(3)},
+	})
+	db.Create(&[]TelemetryRecord{
+		{MachineID: "m1", Version: "3.0", OS: "Windows"},
+		{MachineID: "m2", Version: "3.1", OS: "Linux"},
+	})
+
+	tests := []struct {
+		name   string
+		entity string
+		query  string
+		want   []string // 导出的 message / machine_id 列, 按日期升序
+	}{
+		{name: "no filter", entity: "logs", query: "", want: []string{"a", "b", "c"}},
+		{name: "equality filter", entity: "logs", query: "level=ERROR", want: []string{"a", "c"}},
+		{name: "combined filters", entity: "logs", query: "level=ERROR&machine_id=m2", want: []string{"c"}},
+		{name: "date range", entity: "logs", query: "start_date=2026-03-02&end_date=2026-03-02", want: []string{"b"}},
+		{name: "undeclared param ignored", entity: "logs", query: "message=a", want: []string{"a", "b", "c"}},
+		{name: "value is bound", entity: "logs", query: "level=ERROR'%20OR%20'1'='1", want: nil},
+		{name: "user filter scope", entity: "users", query: "os=Linux", want: []string{"m2"}},
+		{name: "blocked machine excluded", entity: "logs", query: "level=WARN", want: nil},
+		{name: "blocked user excluded", entity: "users", query: "os=Windows", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if strings.HasPrefix(tt.name, "blocked") {
+				addBlock(BlockKindMachineID, "m1", "test", 0)
+			}
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("GET", "/admin/export?"+tt.query, nil)
+
+			e := exportEntities[tt.entity]
+			var buf strings.Builder
+			if err := writeExportCSV(&buf, e, e.exportQuery(c)); err != nil {
+				t.Fatalf("export failed: %v", err)
+			}
+			rows, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(buf.String(), "\xEF\xBB\xBF"))).ReadAll()
+			if err != nil {
+				t.Fatalf("invalid csv: %v", err)
+			}
+
+			col := 0
+			key := "machine_id"
+			if tt.entity == "logs" {
+				key = "message"
+			}
+			for i, name := range e.columns {
+				if name == key {
+					col = i
+				}
+			}
+			var got []string
+			for _, row := range rows[1:] {
+				got = append(got, row[col])
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExportEntityHeaders(t *testing.T) {
+	for name, e := range exportEntities {
+		if len(e.headers) != len(e.columns) {
+			t.Errorf("%s: %d headers for %d columns", name, len(e.headers), len(e.columns))
+		}
+	}
+}
+
+func TestServeExportWritesAudit(t *testing.T) {
+	setupTestDB(t)
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/admin/export?entity=logs&level=ERROR", nil)
+	serveExport(c, "logs", exportEntities["logs"])
+
+	if !strings.HasPrefix(w.Body.String(), "\xEF\xBB\xBFMachine ID,Version,Level") {
+		t.Errorf("unexpected header row: %q", strings.SplitN(w.Body.String(), "\n", 2)[0])
+	}
+	var entries []AuditEntry
+	db.Find(&entries)
+	if len(entries) != 1 || entries[0].Action != "export" || entries[0].Detail != "logs entity=logs&level=ERROR" {
+		t.Errorf("audit entries = %+v", entries)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxFeedbackLen    = 5000
+	maxDiagnosticsLen = 256 * 1024
+)
+
+var feedbackCategories = map[string]bool{"bug": true, "suggestion": true, "question": true, "other": true}
+
+type FeedbackRequest struct {
+	MachineID   string `json:"machine_id"`
+	Version     string `json:"version"`
+	Category    string `json:"category"`
+	Message     string `json:"message"`
+	Diagnostics string `json:"diagnostics"` // 可选的诊断包 (系统信息 + 最近日志)
+}
+
+// toFeedback 校验并规范化一条反馈, 未知分类归为 other, 超长内容截断
+func (r FeedbackRequest) toFeedback() (Feedback, bool) {
+	message := strings.TrimSpace(r.Message)
+	if r.MachineID == "" || message == "" {
+		return Feedback{}, false
+	}
+	message = headBytes(message, maxFeedbackLen)
+	category := strings.ToLower(r.Category)
+	if !feedbackCategories[category] {
+		category = "other"
+	}
+	// 诊断包中最近的日志在末尾, 超长时保留尾部
+	diagnostics := tailBytes(r.Diagnostics, maxDiagnosticsLen)
+	return Feedback{
+		MachineID:      r.MachineID,
+		Version:        r.Version,
+		Category:       category,
+		Message:        message,
+		Diagnostics:    diagnostics,
+		HasDiagnostics: diagnostics != "",
+		Status:         "open",
+	}, true
+}
+
+// headBytes 保留前 max 字节, 截断点回退到字符边界, 避免切开多字节字符
+func headBytes(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return s[:max]
+}
+
+// tailBytes 保留后 max 字节, 截断点前移到字符边界
+func tailBytes(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	start := len(s) - max
+	for start < len(s) && !utf8.RuneStart(s[start]) {
+		start++
+	}
+	return s[start:]
+}
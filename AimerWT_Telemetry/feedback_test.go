@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestFeedbackTruncation(t *testing.T) {
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{name: "head short", got: headBytes("反馈", 10), want: "反馈"},
+		{name: "head on boundary", got: headBytes("反馈内容", 6), want: "反馈"},
+		{name: "head inside rune", got: headBytes("反馈内容", 7), want: "反馈"},
+		{name: "tail on boundary", got: tailBytes("日志末尾", 6), want: "末尾"},
+		{name: "tail inside rune", got: tailBytes("日志末尾", 7), want: "末尾"},
+		{name: "ascii", got: tailBytes("abcdef", 3), want: "def"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+
+	fb, ok := FeedbackRequest{
+		MachineID:   "m1",
+		Category:    "BUG",
+		Message:     "a" + strings.Repeat("错", maxFeedbackLen),
+		Diagnostics: strings.Repeat("志", maxDiagnosticsLen) + "b",
+	}.toFeedback()
+	if !ok {
+		t.Fatal("toFeedback rejected a valid request")
+	}
+	if fb.Category != "bug" {
+		t.Errorf("category = %q, want bug", fb.Category)
+	}
+	if len(fb.Message) > maxFeedbackLen || !utf8.ValidString(fb.Message) {
+		t.Errorf("message: %d bytes, valid UTF-8 %v", len(fb.Message), utf8.ValidString(fb.Message))
+	}
+	if len(fb.Diagnostics) > maxDiagnosticsLen || !utf8.ValidString(fb.Diagnostics) || !strings.HasSuffix(fb.Diagnostics, "b") {
+		t.Errorf("diagnostics: %d bytes, valid UTF-8 %v", len(fb.Diagnostics), utf8.ValidString(fb.Diagnostics))
+	}
+}
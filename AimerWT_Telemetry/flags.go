@@ -0,0 +1,34 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// bucketOf 将机器码稳定地映射到 0-99 的分桶, 同一 seed 下结果不随请求变化
+func bucketOf(seed, machineID string) int {
+	sum := sha256.Sum256([]byte(seed + ":" + machineID))
+	return int(binary.BigEndian.Uint64(sum[:8]) % 100)
+}
+
+func (f FeatureFlag) enabledFor(record TelemetryRecord) bool {
+	if !f.Enabled {
+		return false
+	}
+	if !matchTarget(f.Versions, record.Version) || !matchTarget(f.Locales, record.Locale) {
+		return false
+	}
+	return bucketOf(f.Name, record.MachineID) < f.Percentage
+}
+
+// evaluateFlags 返回该客户端可见的全部功能开关状态
+func evaluateFlags(record TelemetryRecord) map[string]bool {
+	var flags []FeatureFlag
+	db.Find(&flags)
+
+	result := make(map[string]bool, len(flags))
+	for _, f := range flags {
+		result[f.Name] = f.enabledFor(record)
+	}
+	return result
+}
@@ -0,0 +1,48 @@
+package main
+
+import "testing"
+
+func TestMatchTarget(t *testing.T) {
+	tests := []struct {
+		list   string
+		values []string
+		want   bool
+	}{
+		{list: "", values: []string{"anything"}, want: true},
+		{list: " , ", values: []string{"zh-CN"}, want: false},
+		{list: "zh", values: []string{"zh-CN"}, want: true},
+		{list: "ZH-cn", values: []string{"zh-CN"}, want: true},
+		{list: "zh-TW", values: []string{"zh-CN"}, want: false},
+		{list: "en, zh", values: []string{"zh-Hans-CN"}, want: true},
+		{list: "3.0", values: []string{"3.0.1"}, want: false},
+		{list: "3.0, 3.1", values: []string{"3.1"}, want: true},
+		{list: "Windows 7", values: []string{"Windows", "Windows 7"}, want: true},
+	}
+	for _, tt := range tests {
+		if got := matchTarget(tt.list, tt.values...); got != tt.want {
+			t.Errorf("matchTarget(%q, %q) = %v, want %v", tt.list, tt.values, got, tt.want)
+		}
+	}
+}
+
+func TestFlagEnabledFor(t *testing.T) {
+	record := TelemetryRecord{MachineID: "m1", Version: "3.1", Locale: "zh-CN"}
+	tests := []struct {
+		name string
+		flag FeatureFlag
+		want bool
+	}{
+		{name: "disabled", flag: FeatureFlag{Name: "f", Enabled: false, Percentage: 100}, want: false},
+		{name: "everyone", flag: FeatureFlag{Name: "f", Enabled: true, Percentage: 100}, want: true},
+		{name: "nobody", flag: FeatureFlag{Name: "f", Enabled: true, Percentage: 0}, want: false},
+		{name: "locale prefix", flag: FeatureFlag{Name: "f", Enabled: true, Percentage: 100, Locales: "zh"}, want: true},
+		{name: "other locale", flag: FeatureFlag{Name: "f", Enabled: true, Percentage: 100, Locales: "en"}, want: false},
+		{name: "version listed", flag: FeatureFlag{Name: "f", Enabled: true, Percentage: 100, Versions: "3.0, 3.1"}, want: true},
+		{name: "version not listed", flag: FeatureFlag{Name: "f", Enabled: true, Percentage: 100, Versions: "3.0"}, want: false},
+	}
+	for _, tt := range tests {
+		if got := tt.flag.enabledFor(record); got != tt.want {
+			t.Errorf("%s: enabledFor = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
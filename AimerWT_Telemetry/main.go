@@ -1,20 +1,16 @@
 package main
 
 import (
-	_ "embed"
-	"fmt"
+	"flag"
 	"log"
 	"os"
-	"strings"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/glebarez/sqlite"
 	"gorm.io/gorm"
 )
 
-//go:embed dashboard.html
-var dashboardHTML []byte
-
 var sysConfig SystemConfig
 
 var db *gorm.DB
@@ -22,17 +18,40 @@ var db *gorm.DB
 var adminUser = os.Getenv("TELEMETRY_ADMIN_USER")
 var adminPass = os.Getenv("TELEMETRY_ADMIN_PASS")
 
+var dbModels = []any{&TelemetryRecord{}, &SessionRecord{}, &BlockEntry{}, &Segment{}, &Announcement{}, &AnnouncementReceipt{}, &FeatureFlag{}, &Experiment{}, &ExperimentEvent{}, &AnomalyEvent{}, &ClientLog{}, &Feedback{}, &PackAuthor{}, &RepoPack{}, &DownloadLink{}, &DownloadEvent{}, &CommandReceipt{}, &AuditEntry{}}
+
 func initDB() {
 	var err error
 	db, err = gorm.Open(sqlite.Open("telemetry.db"), &gorm.Config{})
 	if err != nil {
 		log.Fatalf("数据库连接失败: %v", err)
 	}
-	db.AutoMigrate(&TelemetryRecord{})
+	db.AutoMigrate(dbModels...)
+	backfillNormalizedFields()
 }
 
 func main() {
+	seedDemo := flag.Bool("seed-demo", false, "生成演示数据后退出")
+	seedUsers := flag.Int("seed-users", 500, "演示用户数量")
+	seedDays := flag.Int("seed-days", 60, "演示数据覆盖的天数")
+	clearDemo := flag.Bool("clear-demo", false, "删除演示数据后退出")
+	flag.Parse()
+
 	initDB()
+
+	if *clearDemo {
+		if err := clearDemoData(); err != nil {
+			log.Fatalf("删除演示数据失败: %v", err)
+		}
+		log.Println("已删除演示数据")
+		return
+	}
+	if *seedDemo {
+		if err := seedDemoData(*seedUsers, *seedDays); err != nil {
+			log.Fatalf("生成演示数据失败: %v", err)
+		}
+		return
+	}
 	r := gin.Default()
 
 	if adminUser == "" || adminPass == "" {
@@ -40,21 +59,24 @@ func main() {
 	}
 
 	initRouter(r)
+	startBackupScheduler()
+	startAnomalyDetector()
+	startDeletedUserPurger()
 
 	log.Println("遥测后端已启动在 :8080")
 	r.Run(":8080")
 }
 
-func buildWhereClause(c *gin.Context) string {
-	var clauses []string
-	if value := c.Query("value"); value != "" {
-		clauses = append(clauses, fmt.Sprintf("value = '%s'", value))
-	}
-	if arch := c.Query("arch"); arch != "" {
-		clauses = append(clauses, fmt.Sprintf("arch = '%s'", arch))
+func envString(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
 	}
-	if len(clauses) > 0 {
-		return " AND " + strings.Join(clauses, " AND ")
+	return def
+}
+
+func envInt(key string, def int) int {
+	if v, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return v
 	}
-	return ""
+	return def
 }
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// setupTestDB 将全局 db 替换为临时目录下的独立数据库, 测试结束后恢复
+func setupTestDB(t *testing.T) {
+	t.Helper()
+	testDB, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open test db: %v", err)
+	}
+	if err := testDB.AutoMigrate(dbModels...); err != nil {
+		t.Fatalf("migrate test db: %v", err)
+	}
+	prev := db
+	db = testDB
+	t.Cleanup(func() {
+		db = prev
+		if sqlDB, err := testDB.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"errors"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var errNoMergeSources = errors.New("no source records")
+
+type DuplicateGroup struct {
+	Reason   string   `json:"reason"`
+	Machines []string `json:"machines"`
+}
+
+// mergeRecords 将 sources 合并进 target: 保留最早的 created_at 与最近的 last_seen_at, 合并别名, 迁移待执行命令及各表的关联数据
+func mergeRecords(target string, sources []string) (TelemetryRecord, error) {
+	var merged TelemetryRecord
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("machine_id = ?", target).First(&merged).Error; err != nil {
+			return err
+		}
+
+		var others []TelemetryRecord
+		if err := tx.Where("machine_id IN ? AND machine_id <> ?", sources, target).Find(&others).Error; err != nil {
+			return err
+		}
+		if len(others) == 0 {
+			return errNoMergeSources
+		}
+
+		aliases := []string{}
+		addAlias := func(a string) {
+			for _, part := range strings.Split(a, " / ") {
+				part = strings.TrimSpace(part)
+				if part == "" {
+					continue
+				}
+				for _, existing := range aliases {
+					if existing == part {
+						return
+					}
+				}
+				aliases = append(aliases, part)
+			}
+		}
+		addAlias(merged.Alias)
+
+		var ids []string
+		for _, o := range others {
+			ids = append(ids, o.MachineID)
+			addAlias(o.Alias)
+			if o.CreatedAt.Before(merged.CreatedAt) {
+				merged.CreatedAt = o.CreatedAt
+			}
+			if o.LastSeenAt.After(merged.LastSeenAt) {
+				merged.LastSeenAt = o.LastSeenAt
+			}
+			if merged.PendingCommand == "" {
+				merged.PendingCommand = o.PendingCommand
+			}
+		}
+		merged.Alias = strings.Join(aliases, " / ")
+
+		if err := tx.Model(&TelemetryRecord{}).Where("id = ?", merged.ID).UpdateColumns(map[string]any{
+			"alias":           merged.Alias,
+			"created_at":      merged.CreatedAt,
+			"last_seen_at":    merged.LastSeenAt,
+			"pending_command": merged.PendingCommand,
+		}).Error; err != nil {
+			return err
+		}
+		if err := reassignMachineRows(tx, target, ids); err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("machine_id IN ?", ids).Delete(&TelemetryRecord{}).Error
+	})
+	return merged, err
+}
+
+// reassignMachineRows 把各表中按机器码记录的数据迁移到 target, 与 target 唯一键冲突的行保留 target 的一份
+func reassignMachineRows(tx *gorm.DB, target string, sources []string) error {
+	conflicts := map[string]string{
+		"session_records":       "session_id",
+		"announcement_receipts": "announcement_id",
+	}
+	tables := []string{"session_records", "command_receipts", "client_logs", "feedbacks", "experiment_events", "announcement_receipts"}
+	for _, table := range tables {
+		// 逐个迁移, 避免多个来源机器码之间的唯一键冲突
+		for _, source := range sources {
+			if key, ok := conflicts[table]; ok {
+				if err := tx.Exec("DELETE FROM "+table+" WHERE machine_id = ? AND "+key+" IN (SELECT "+key+" FROM "+table+" WHERE machine_id = ?)", source, target).Error; err != nil {
+					return err
+				}
+			}
+			if err := tx.Exec("UPDATE "+table+" SET machine_id = ? WHERE machine_id = ?", target, source).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// findDuplicates 按硬件/环境特征启发式分组, 同一组内的机器码可能属于同一用户
+func findDuplicates() []DuplicateGroup {
+	heuristics := []struct {
+		reason string
+		fields string
+	}{
+		{"same_ip_and_hardware", "COALESCE(last_ip, ''), os, os_version, arch, cpu_count, screen_res"},
+		{"same_alias", "alias"},
+	}
+
+	groups := []DuplicateGroup{}
+	for _, h := range heuristics {
+		var rows []struct {
+			Machines string
+		}
+		q := db.Model(&TelemetryRecord{}).
+			Select("group_concat(machine_id, ',') as machines").
+			Group(h.fields).
+			Having("count(*) > 1")
+		if h.reason == "same_alias" {
+			q = q.Where("alias <> ''")
+		} else {
+			q = q.Where("COALESCE(last_ip, '') <> ''")
+		}
+		q.Scan(&rows)
+
+		for _, row := range rows {
+			groups = append(groups, DuplicateGroup{Reason: h.reason, Machines: strings.Split(row.Machines, ",")})
+		}
+	}
+	return groups
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestMergeRecordsErrors(t *testing.T) {
+	setupTestDB(t)
+	db.Create(&[]TelemetryRecord{
+		{MachineID: "a", Version: "3.0", Alias: "home"},
+		{MachineID: "b", Version: "3.0", Alias: "laptop"},
+	})
+
+	tests := []struct {
+		name    string
+		target  string
+		sources []string
+		wantErr error
+	}{
+		{name: "missing target", target: "zz", sources: []string{"a"}, wantErr: gorm.ErrRecordNotFound},
+		{name: "no sources", target: "a", sources: []string{"a", "zz"}, wantErr: errNoMergeSources},
+		{name: "merged", target: "a", sources: []string{"b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, err := mergeRecords(tt.target, tt.sources)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && merged.Alias != "home / laptop" {
+				t.Errorf("alias = %q, want %q", merged.Alias, "home / laptop")
+			}
+		})
+	}
+}
@@ -1,6 +1,10 @@
 package main
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type TelemetryRecord struct {
 	ID             uint      `gorm:"primaryKey;autoIncrement" json:"id"`
@@ -13,12 +17,204 @@ type TelemetryRecord struct {
 	Arch           string    `json:"arch"`
 	CPUCount       int       `json:"cpu_count"`
 	ScreenRes      string    `json:"screen_res"`
+	ScreenBucket   string    `gorm:"index" json:"screen_bucket"`
+	OSName         string    `gorm:"index" json:"os_name"`
 	PythonVersion  string    `json:"python_version"`
 	Locale         string    `json:"locale"`
+	Channel        string    `gorm:"default:stable" json:"channel"`
+	Source         string    `gorm:"index" json:"source"` // 首次上报时的安装来源, 之后不再覆盖
+	GameVersion    string    `gorm:"index" json:"game_version"`
 	SessionID      int       `json:"session_id"`
 	PendingCommand string    `json:"pending_command"`
+	LastIP         string    `json:"-"`
 	LastSeenAt     time.Time `gorm:"autoUpdateTime" json:"last_seen_at"`
 	CreatedAt      time.Time `gorm:"autoCreateTime" json:"created_at"`
+
+	// 管理端删除为软删除, 保留期内可恢复, 机器重新上报时也会自动恢复
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+}
+
+// SessionRecord 一次客户端运行会话, 由 machine_id + session_id 唯一确定
+type SessionRecord struct {
+	ID         uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	MachineID  string     `gorm:"uniqueIndex:idx_session;type:varchar(64)" json:"machine_id"`
+	SessionID  int        `gorm:"uniqueIndex:idx_session" json:"session_id"`
+	Version    string     `json:"version"`
+	StartedAt  time.Time  `gorm:"index" json:"started_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	EndedAt    *time.Time `json:"ended_at"`
+}
+
+type BlockEntry struct {
+	ID        uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	Kind      string     `gorm:"uniqueIndex:idx_block_kind_value;type:varchar(16)" json:"kind"`
+	Value     string     `gorm:"uniqueIndex:idx_block_kind_value;type:varchar(64)" json:"value"`
+	Reason    string     `json:"reason"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	CreatedAt time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// Segment 保存的用户分群, Filter 为 UserFilter 的 JSON
+type Segment struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name      string    `gorm:"uniqueIndex;type:varchar(64)" json:"name"`
+	Filter    string    `json:"filter"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+type Announcement struct {
+	ID        uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	Kind      string     `gorm:"index;type:varchar(16)" json:"kind"`
+	Active    bool       `json:"active"`
+	Title     string     `json:"title"`
+	Content   string     `json:"content"`
+	Url       string     `json:"url"`
+	Scope     string     `json:"scope"`
+	Locales   string     `json:"locales"`
+	OS        string     `json:"os"`
+	Channel   string     `json:"channel"`
+	StartAt   *time.Time `json:"start_at"`
+	EndAt     *time.Time `json:"end_at"`
+	CreatedAt time.Time  `gorm:"autoCreateTime" json:"created_at"`
+
+	Reach int64 `gorm:"-" json:"reach"`
+	Read  int64 `gorm:"-" json:"read"`
+}
+
+// AnnouncementReceipt 记录公告送达与已读, 每台机器每条公告一行
+type AnnouncementReceipt struct {
+	ID             uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	AnnouncementID uint       `gorm:"uniqueIndex:idx_receipt" json:"announcement_id"`
+	MachineID      string     `gorm:"uniqueIndex:idx_receipt;type:varchar(64)" json:"machine_id"`
+	DeliveredAt    time.Time  `json:"delivered_at"`
+	ReadAt         *time.Time `json:"read_at"`
+}
+
+type FeatureFlag struct {
+	ID          uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name        string    `gorm:"uniqueIndex;type:varchar(64)" json:"name"`
+	Description string    `json:"description"`
+	Enabled     bool      `json:"enabled"`
+	Versions    string    `json:"versions"`   // 逗号分隔, 为空表示全部版本
+	Locales     string    `json:"locales"`    // 逗号分隔, 为空表示全部区域
+	Percentage  int       `json:"percentage"` // 0-100 灰度比例
+	UpdatedAt   time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+type Experiment struct {
+	ID          uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name        string    `gorm:"uniqueIndex;type:varchar(64)" json:"name"`
+	Description string    `json:"description"`
+	Variants    string    `json:"variants"`   // 逗号分隔, 例如 control,treatment
+	Percentage  int       `json:"percentage"` // 参与实验的流量比例 0-100
+	Active      bool      `json:"active"`
+	CreatedAt   time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+type ExperimentEvent struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Experiment string    `gorm:"index;type:varchar(64)" json:"experiment"`
+	Variant    string    `json:"variant"`
+	MachineID  string    `gorm:"index;type:varchar(64)" json:"machine_id"`
+	Kind       string    `json:"kind"` // exposure 或 conversion
+	Goal       string    `json:"goal"`
+	CreatedAt  time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+type AnomalyEvent struct {
+	ID           uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Metric       string    `gorm:"index;type:varchar(32)" json:"metric"`
+	Value        float64   `json:"value"`
+	Baseline     float64   `json:"baseline"`
+	Direction    string    `json:"direction"` // spike 或 drop
+	Acknowledged bool      `json:"acknowledged"`
+	CreatedAt    time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+type ClientLog struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	MachineID string    `gorm:"index;type:varchar(64)" json:"machine_id"`
+	Version   string    `gorm:"index" json:"version"`
+	Level     string    `gorm:"index;type:varchar(8)" json:"level"`
+	Message   string    `json:"message"`
+	LoggedAt  time.Time `json:"logged_at"`
+	CreatedAt time.Time `gorm:"autoCreateTime;index" json:"created_at"`
+}
+
+type Feedback struct {
+	ID             uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	MachineID      string    `gorm:"index;type:varchar(64)" json:"machine_id"`
+	Version        string    `json:"version"`
+	Category       string    `gorm:"index;type:varchar(16)" json:"category"`
+	Message        string    `json:"message"`
+	Diagnostics    string    `json:"-"`
+	HasDiagnostics bool      `json:"has_diagnostics"`
+	Status         string    `gorm:"index;type:varchar(16)" json:"status"` // open 或 resolved
+	CreatedAt      time.Time `gorm:"autoCreateTime;index" json:"created_at"`
+}
+
+// PackAuthor 已登记的资源包作者, 通过 Bearer 令牌发布资源包元数据
+type PackAuthor struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name      string    `gorm:"uniqueIndex;type:varchar(64)" json:"name"`
+	TokenHash string    `gorm:"index;type:varchar(64)" json:"-"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// RepoPack 在线资源索引中的一个资源包, 按名称唯一, 重新发布即更新版本
+type RepoPack struct {
+	ID          uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	AuthorID    uint      `gorm:"index" json:"author_id"`
+	Name        string    `gorm:"uniqueIndex;type:varchar(64)" json:"name"`
+	Version     string    `json:"version"`
+	Language    string    `json:"language"`
+	Description string    `json:"description"`
+	Hash        string    `json:"hash"`
+	Size        int64     `json:"size"`
+	DownloadUrl string    `json:"download_url"`
+	CreatedAt   time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// DownloadLink 托管的下载短链, /download/<name> 统计后跳转到 Url
+type DownloadLink struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name      string    `gorm:"uniqueIndex;type:varchar(64)" json:"name"`
+	Version   string    `json:"version"`
+	Url       string    `json:"url"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+type DownloadEvent struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	LinkName  string    `gorm:"index;type:varchar(64)" json:"link_name"`
+	Version   string    `gorm:"index" json:"version"`
+	MachineID string    `json:"machine_id"`
+	IPHash    string    `gorm:"type:varchar(16)" json:"-"`
+	CreatedAt time.Time `gorm:"autoCreateTime;index" json:"created_at"`
+}
+
+// CommandReceipt 记录一次用户指令的下发与客户端执行结果
+type CommandReceipt struct {
+	ID          uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	MachineID   string     `gorm:"index;type:varchar(64)" json:"machine_id"`
+	Type        string     `gorm:"index;type:varchar(32)" json:"type"`
+	Version     string     `gorm:"index" json:"version"`
+	Status      string     `gorm:"index;type:varchar(16)" json:"status"` // delivered, executed 或 failed
+	Error       string     `json:"error"`
+	DeliveredAt time.Time  `gorm:"index" json:"delivered_at"`
+	AckedAt     *time.Time `json:"acked_at"`
+}
+
+// AuditEntry 管理端操作审计记录, 目前记录数据导出
+type AuditEntry struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Action    string    `gorm:"index;type:varchar(32)" json:"action"`
+	Detail    string    `json:"detail"`
+	IP        string    `json:"ip"`
+	CreatedAt time.Time `gorm:"autoCreateTime;index" json:"created_at"`
 }
 
 type StatsResponse struct {
@@ -26,17 +222,23 @@ type StatsResponse struct {
 	OnlineUsers    int64            `json:"online_users"`
 	TodayNew       int64            `json:"today_new"`
 	DAU            int64            `json:"dau"`
+	OnlineMinutes  int              `json:"online_minutes"`
 	OSStats        []map[string]any `json:"os_stats"`
 	ArchStats      []map[string]any `json:"arch_stats"`
 	VersionStats   []map[string]any `json:"version_stats"`
 	LocaleStats    []map[string]any `json:"locale_stats"`
 	ScreenStats    []map[string]any `json:"screen_stats"`
+	OSBuildStats   []map[string]any `json:"os_build_stats"`
+	ChannelStats   []map[string]any `json:"channel_stats"`
+	SourceStats    []map[string]any `json:"source_stats"`
+	GameStats      []map[string]any `json:"game_version_stats"`
 	GrowthData     []map[string]any `json:"growth_data"`
 	RecentUsers    []map[string]any `json:"recent_users"`
 	OSOptions      []map[string]any `json:"os_options"`
 	ArchOptions    []map[string]any `json:"arch_options"`
 	VersionOptions []map[string]any `json:"version_options"`
 	LocaleOptions  []map[string]any `json:"locale_options"`
+	ChannelOptions []map[string]any `json:"channel_options"`
 }
 
 type DrilldownResponse struct {
@@ -49,19 +251,42 @@ type SystemConfig struct {
 	MaintenanceMsg string `json:"maintenance_msg"`
 	StopNewData    bool   `json:"stop_new_data"`
 
-	// 紧急通知 (弹窗/模态)
+	// 紧急通知 (弹窗/模态) 与常驻公告 (覆盖公告栏文字) 保存在 Announcement 表中, 下发时按客户端填充
 	AlertActive  bool   `json:"alert_active"`
+	AlertID      uint   `json:"alert_id"`
 	AlertTitle   string `json:"alert_title"`
 	AlertContent string `json:"alert_content"`
-	AlertScope   string `json:"alert_scope"`
 
-	// 常驻公告 (覆盖公告栏文字)
 	NoticeActive  bool   `json:"notice_active"`
+	NoticeID      uint   `json:"notice_id"`
 	NoticeContent string `json:"notice_content"`
-	NoticeScope   string `json:"notice_scope"`
 
 	UpdateActive  bool   `json:"update_active"`
 	UpdateContent string `json:"update_content"`
 	UpdateUrl     string `json:"update_url"`
 	UpdateScope   string `json:"update_scope"`
+
+	// 最低支持版本, 低于该版本的客户端会收到 compat.supported = false
+	MinVersion    string `json:"min_version"`
+	MinVersionMsg string `json:"min_version_msg"`
+	ForceUpdate   bool   `json:"force_update"`
+
+	// 已知存在严重问题的版本 (逗号分隔), 命中的客户端会禁用安装操作并强制提示更新
+	UnsafeVersions   string `json:"unsafe_versions"`
+	UnsafeVersionMsg string `json:"unsafe_version_msg"`
+
+	// 按更新通道覆盖的更新提示与公告, 仅在后台保存, 下发前会按客户端通道展开
+	Channels map[string]ChannelConfig `json:"channels,omitempty"`
+}
+
+// ChannelConfig 通道覆盖配置, UpdateSet/NoticeSet 表示该通道显式设置过对应项,
+// 此时即使 Active 为 false 也会覆盖全局配置 (用于对某个通道单独关闭).
+// 通道公告的内容、排期与定向由 activeAnnouncement 按通道查询, 这里只记录是否对该通道关闭
+type ChannelConfig struct {
+	UpdateSet     bool   `json:"update_set"`
+	UpdateActive  bool   `json:"update_active"`
+	UpdateContent string `json:"update_content"`
+	UpdateUrl     string `json:"update_url"`
+	NoticeSet     bool   `json:"notice_set"`
+	NoticeActive  bool   `json:"notice_active"`
 }
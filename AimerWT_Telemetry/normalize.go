@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// windowsBuilds 将 Windows 内部版本号映射为常用的版本名称
+var windowsBuilds = map[int]string{
+	7600:  "Win7",
+	7601:  "Win7 SP1",
+	9200:  "Win8",
+	9600:  "Win8.1",
+	10240: "Win10 1507",
+	10586: "Win10 1511",
+	14393: "Win10 1607",
+	15063: "Win10 1703",
+	16299: "Win10 1709",
+	17134: "Win10 1803",
+	17763: "Win10 1809",
+	18362: "Win10 1903",
+	18363: "Win10 1909",
+	19041: "Win10 2004",
+	19042: "Win10 20H2",
+	19043: "Win10 21H1",
+	19044: "Win10 21H2",
+	19045: "Win10 22H2",
+	22000: "Win11 21H2",
+	22621: "Win11 22H2",
+	22631: "Win11 23H2",
+	26100: "Win11 24H2",
+	26200: "Win11 25H2",
+}
+
+// friendlyOSName 生成便于统计的系统名称, Windows 按内部版本号归类, 其他系统保留名称与主版本
+func friendlyOSName(osName, release, version string) string {
+	if osName != "Windows" {
+		if release == "" {
+			return osName
+		}
+		return osName + " " + strings.SplitN(release, ".", 2)[0]
+	}
+
+	fallback := strings.TrimSpace("Windows " + release)
+	parts := strings.Split(version, ".")
+	if len(parts) < 3 {
+		return fallback
+	}
+	build, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return fallback
+	}
+	if name, ok := windowsBuilds[build]; ok {
+		return name
+	}
+	switch {
+	case build >= 22000:
+		return fmt.Sprintf("Win11 (%d)", build)
+	case build >= 10240:
+		return fmt.Sprintf("Win10 (%d)", build)
+	}
+	return fallback
+}
+
+// screenBucket 将原始分辨率归入常见档位, 宽高比超过 21:9 附近的统一归为带鱼屏
+func screenBucket(raw string) string {
+	w, h, ok := strings.Cut(strings.ToLower(strings.TrimSpace(raw)), "x")
+	if !ok {
+		return "unknown"
+	}
+	width, err1 := strconv.Atoi(strings.TrimSpace(w))
+	height, err2 := strconv.Atoi(strings.TrimSpace(h))
+	if err1 != nil || err2 != nil || width <= 0 || height <= 0 {
+		return "unknown"
+	}
+	if height > width {
+		width, height = height, width
+	}
+	if float64(width)/float64(height) >= 2.1 {
+		return "Ultrawide"
+	}
+
+	switch {
+	case height < 720:
+		return "<720p"
+	case height < 900:
+		return "720p"
+	case height < 1080:
+		return "900p"
+	case height < 1440:
+		return "1080p"
+	case height < 2160:
+		return "1440p"
+	case height < 2880:
+		return "4K"
+	}
+	return "5K+"
+}
+
+func normalizeRecord(r *TelemetryRecord) {
+	r.ScreenBucket = screenBucket(r.ScreenRes)
+	r.OSName = friendlyOSName(r.OS, r.OSRelease, r.OSVersion)
+}
+
+// backfillNormalizedFields 为升级前写入的记录补齐归一化字段
+func backfillNormalizedFields() {
+	var records []TelemetryRecord
+	db.Where("screen_bucket IS NULL OR screen_bucket = ''").
+		Select("id", "os", "os_release", "os_version", "screen_res").
+		Find(&records)
+
+	for i := range records {
+		normalizeRecord(&records[i])
+		db.Model(&TelemetryRecord{}).Where("id = ?", records[i].ID).UpdateColumns(map[string]any{
+			"screen_bucket": records[i].ScreenBucket,
+			"os_name":       records[i].OSName,
+		})
+	}
+	if len(records) > 0 {
+		log.Printf("已补齐 %d 条记录的分辨率档位与系统名称", len(records))
+	}
+}
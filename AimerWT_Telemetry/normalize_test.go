@@ -0,0 +1,98 @@
+package main
+
+import "testing"
+
+func TestScreenBucket(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"1920x1080", "1080p"},
+		{"1080x1920", "1080p"},
+		{" 1920 X 1080 ", "1080p"},
+		{"1920x1200", "1080p"},
+		{"2560x1440", "1440p"},
+		{"1280x720", "720p"},
+		{"1366x768", "720p"},
+		{"1600x900", "900p"},
+		{"800x600", "<720p"},
+		{"3840x2160", "4K"},
+		{"5120x2880", "5K+"},
+		{"3440x1440", "Ultrawide"},
+		{"2560x1080", "Ultrawide"},
+		{"5120x1440", "Ultrawide"},
+		{"", "unknown"},
+		{"1920", "unknown"},
+		{"axb", "unknown"},
+		{"0x0", "unknown"},
+		{"-1920x1080", "unknown"},
+	}
+	for _, tt := range tests {
+		if got := screenBucket(tt.raw); got != tt.want {
+			t.Errorf("screenBucket(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestFriendlyOSName(t *testing.T) {
+	tests := []struct {
+		os, release, version string
+		want                 string
+	}{
+		{"Windows", "10", "10.0.19045", "Win10 22H2"},
+		{"Windows", "10", "10.0.22631", "Win11 23H2"},
+		{"Windows", "7", "6.1.7601", "Win7 SP1"},
+		{"Windows", "10", "10.0.22700", "Win11 (22700)"},
+		{"Windows", "10", "10.0.19999", "Win10 (19999)"},
+		{"Windows", "Vista", "6.0.6002", "Windows Vista"},
+		{"Windows", "10", "10.0", "Windows 10"},
+		{"Windows", "10", "10.0.x", "Windows 10"},
+		{"Windows", "", "", "Windows"},
+		{"Windows", "", "10.0.abc", "Windows"},
+		{"Linux", "6.5.0-14-generic", "", "Linux 6"},
+		{"Darwin", "23.1.0", "", "Darwin 23"},
+		{"Linux", "", "", "Linux"},
+	}
+	for _, tt := range tests {
+		if got := friendlyOSName(tt.os, tt.release, tt.version); got != tt.want {
+			t.Errorf("friendlyOSName(%q, %q, %q) = %q, want %q", tt.os, tt.release, tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeRecord(t *testing.T) {
+	tests := []struct {
+		name       string
+		record     TelemetryRecord
+		wantBucket string
+		wantOSName string
+	}{
+		{
+			name:       "windows",
+			record:     TelemetryRecord{OS: "Windows", OSRelease: "10", OSVersion: "10.0.26100", ScreenRes: "2560x1440"},
+			wantBucket: "1440p",
+			wantOSName: "Win11 24H2",
+		},
+		{
+			name:       "linux without screen",
+			record:     TelemetryRecord{OS: "Linux", OSRelease: "6.8.0"},
+			wantBucket: "unknown",
+			wantOSName: "Linux 6",
+		},
+		{
+			name:       "overwrites stale values",
+			record:     TelemetryRecord{OS: "Windows", ScreenRes: "1280x720", ScreenBucket: "4K", OSName: "Win7"},
+			wantBucket: "720p",
+			wantOSName: "Windows",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := tt.record
+			normalizeRecord(&r)
+			if r.ScreenBucket != tt.wantBucket || r.OSName != tt.wantOSName {
+				t.Errorf("got (%q, %q), want (%q, %q)", r.ScreenBucket, r.OSName, tt.wantBucket, tt.wantOSName)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// deletedRetentionDays 管理端删除的用户先软删除, 超过保留期后才会被物理清除
+var deletedRetentionDays = envInt("TELEMETRY_DELETED_RETENTION_DAYS", 30)
+
+// purgeInactive 删除超过 days 天未上报的机器记录与过期的客户端日志, 返回各自删除的行数
+func purgeInactive(days int) (records, logs int64, err error) {
+	cutoff := time.Now().AddDate(0, 0, -days)
+
+	res := db.Unscoped().Where("last_seen_at < ?", cutoff).Delete(&TelemetryRecord{})
+	if res.Error != nil {
+		return 0, 0, res.Error
+	}
+	records = res.RowsAffected
+
+	res = db.Where("created_at < ?", cutoff).Delete(&ClientLog{})
+	if res.Error != nil {
+		return records, 0, res.Error
+	}
+	return records, res.RowsAffected, nil
+}
+
+func listDeletedUsers() []TelemetryRecord {
+	var users []TelemetryRecord
+	db.Unscoped().Where("deleted_at IS NOT NULL").Order("deleted_at desc").Find(&users)
+	return users
+}
+
+func restoreUser(machineID string) (int64, error) {
+	res := db.Unscoped().Model(&TelemetryRecord{}).
+		Where("machine_id = ? AND deleted_at IS NOT NULL", machineID).
+		Update("deleted_at", nil)
+	return res.RowsAffected, res.Error
+}
+
+// purgeDeletedUsers 物理删除软删除时间超过保留期的记录
+func purgeDeletedUsers() (int64, error) {
+	cutoff := time.Now().AddDate(0, 0, -deletedRetentionDays)
+	res := db.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).Delete(&TelemetryRecord{})
+	return res.RowsAffected, res.Error
+}
+
+func startDeletedUserPurger() {
+	if deletedRetentionDays <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for range ticker.C {
+			if n, err := purgeDeletedUsers(); err != nil {
+				log.Printf("清除已删除用户失败: %v", err)
+			} else if n > 0 {
+				log.Printf("已清除 %d 条超过保留期的已删除用户", n)
+			}
+		}
+	}()
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+var packHashPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+func hashAuthorToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// createPackAuthor 注册作者并返回一次性展示的明文令牌, 库中只保存其哈希
+func createPackAuthor(name string) (PackAuthor, string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return PackAuthor{}, "", err
+	}
+	token := hex.EncodeToString(buf)
+	author := PackAuthor{Name: name, TokenHash: hashAuthorToken(token), Active: true}
+	err := db.Create(&author).Error
+	return author, token, err
+}
+
+// authorAuthMiddleware 校验 Authorization: Bearer <token>, 通过后将作者写入上下文
+func authorAuthMiddleware(c *gin.Context) {
+	token := strings.TrimSpace(strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "))
+	var author PackAuthor
+	if token == "" || db.Where("token_hash = ? AND active = ?", hashAuthorToken(token), true).First(&author).Error != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	c.Set("author", author)
+	c.Next()
+}
+
+type PackPublishRequest struct {
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	Language    string `json:"language"`
+	Description string `json:"description"`
+	Hash        string `json:"hash"` // 压缩包 SHA-256
+	Size        int64  `json:"size"`
+	DownloadUrl string `json:"download_url"`
+}
+
+func (r PackPublishRequest) validate() error {
+	if r.Name == "" || len(r.Name) > 64 {
+		return errors.New("invalid name")
+	}
+	if r.Version == "" || len(r.Version) > 32 {
+		return errors.New("invalid version")
+	}
+	if !packHashPattern.MatchString(strings.ToLower(r.Hash)) {
+		return errors.New("hash must be a sha256 hex digest")
+	}
+	u, err := url.Parse(r.DownloadUrl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("invalid download_url")
+	}
+	if len(r.Description) > 2000 {
+		return errors.New("description too long")
+	}
+	return nil
+}
+
+// publishPack 新建或更新作者名下的资源包, 同名资源包属于其他作者时拒绝
+func publishPack(author PackAuthor, req PackPublishRequest) (RepoPack, error) {
+	var pack RepoPack
+	err := db.Where("name = ?", req.Name).First(&pack).Error
+	if err == nil && pack.AuthorID != author.ID {
+		return pack, errors.New("pack name is owned by another author")
+	}
+
+	pack.Name = req.Name
+	pack.AuthorID = author.ID
+	pack.Version = req.Version
+	pack.Language = req.Language
+	pack.Description = req.Description
+	pack.Hash = strings.ToLower(req.Hash)
+	pack.Size = req.Size
+	pack.DownloadUrl = req.DownloadUrl
+	return pack, db.Save(&pack).Error
+}
+
+// repositoryIndex 供客户端浏览页使用的在线资源索引
+func repositoryIndex() []map[string]any {
+	items := []map[string]any{}
+	db.Table("repo_packs").
+		Select("repo_packs.name, repo_packs.version, repo_packs.language, repo_packs.description, repo_packs.hash, repo_packs.size, repo_packs.download_url, repo_packs.updated_at, pack_authors.name as author").
+		Joins("JOIN pack_authors ON pack_authors.id = repo_packs.author_id AND pack_authors.active = ?", true).
+		Order("repo_packs.updated_at desc").
+		Scan(&items)
+	return items
+}
@@ -1,7 +1,7 @@
 package main
 
 import (
-	"encoding/csv"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -24,6 +24,17 @@ func initRouter(r *gin.Engine) {
 		c.AbortWithStatus(http.StatusUnauthorized)
 	}
 
+	// 客户端上报接口, 仅允许桌面端 User-Agent 访问
+	clientPaths := map[string]bool{
+		"/telemetry":        true,
+		"/logs":             true,
+		"/feedback":         true,
+		"/ack":              true,
+		"/experiment-event": true,
+		"/session-event":    true,
+		"/command-ack":      true,
+	}
+
 	r.Use(func(c *gin.Context) {
 		path := c.Request.URL.Path
 		if path == "/health" {
@@ -31,12 +42,20 @@ func initRouter(r *gin.Engine) {
 			return
 		}
 
-		if path == "/telemetry" {
+		if clientPaths[path] {
 			ua := c.GetHeader("User-Agent")
 			if len(ua) < 14 || ua[:14] != "AimerWT-Client" {
 				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Access Denied"})
 				return
 			}
+
+			limit := maxClientBodyBytes
+			if path == "/telemetry" {
+				limit = maxTelemetryBodyBytes
+			}
+			if !limitBody(c, limit) {
+				return
+			}
 			c.Next()
 			return
 		}
@@ -45,44 +64,46 @@ func initRouter(r *gin.Engine) {
 
 	authorized := r.Group("/", authMiddleware)
 	{
-		authorized.GET("/dashboard", func(c *gin.Context) {
-			c.Data(http.StatusOK, "text/html; charset=utf-8", dashboardHTML)
-		})
+		authorized.GET("/dashboard", renderDashboard)
+		authorized.GET("/dashboard/assets/*filepath", serveDashboardAsset)
 
 		admin := authorized.Group("/admin")
+		admin.Use(func(c *gin.Context) {
+			c.Next()
+			// 管理端的写操作可能改变统计结果, 成功后清空统计缓存
+			if c.Request.Method == http.MethodPost && c.Writer.Status() == http.StatusOK {
+				invalidateStatsCache()
+			}
+		})
 		{
 			admin.GET("/stats", func(c *gin.Context) {
+				cacheKey := c.Request.URL.RawQuery
+				if cached, ok := getCachedStats(cacheKey); ok {
+					c.JSON(200, cached)
+					return
+				}
+
 				rangeDays := c.DefaultQuery("range", "30")
 				days, _ := strconv.Atoi(rangeDays)
 				if days <= 0 {
 					days = 30
 				}
 
-				baseQuery := db.Model(&TelemetryRecord{})
-				if osFilter := c.Query("os"); osFilter != "" {
-					baseQuery = baseQuery.Where("os = ?", osFilter)
-				}
-				if archFilter := c.Query("arch"); archFilter != "" {
-					baseQuery = baseQuery.Where("arch = ?", archFilter)
-				}
-				if versionFilter := c.Query("version"); versionFilter != "" {
-					baseQuery = baseQuery.Where("version = ?", versionFilter)
-				}
-				if localeFilter := c.Query("locale"); localeFilter != "" {
-					baseQuery = baseQuery.Where("locale = ?", localeFilter)
-				}
+				var filter UserFilter
+				c.ShouldBindQuery(&filter)
+				baseQuery := filter.apply(excludeBlocked(db.Model(&TelemetryRecord{})))
 
 				var stats StatsResponse
 
 				baseQuery.Count(&stats.TotalUsers)
 
-				onlineThreshold := time.Now().Add(-2 * time.Minute)
-				baseQuery.Session(&gorm.Session{}).Where("last_seen_at > ?", onlineThreshold).Count(&stats.OnlineUsers)
+				stats.OnlineMinutes = queryIntInRange(c, "online_minutes", onlineMinutes, 1, 1440)
+				baseQuery.Session(&gorm.Session{}).Where("last_seen_at > ?", onlineSince(stats.OnlineMinutes)).Count(&stats.OnlineUsers)
 
 				today := time.Now().Format("2006-01-02")
 				baseQuery.Session(&gorm.Session{}).Where("date(created_at) = ?", today).Count(&stats.TodayNew)
 
-				dauThreshold := time.Now().Add(-24 * time.Hour)
+				dauThreshold := time.Now().Add(-time.Duration(queryIntInRange(c, "dau_hours", dauHours, 1, 24*30)) * time.Hour)
 				baseQuery.Session(&gorm.Session{}).Where("last_seen_at > ?", dauThreshold).Count(&stats.DAU)
 
 				limit := 8
@@ -97,22 +118,21 @@ func initRouter(r *gin.Engine) {
 				stats.ArchStats = getDistribution("arch")
 				stats.VersionStats = getDistribution("version")
 				stats.LocaleStats = getDistribution("locale")
-				stats.ScreenStats = getDistribution("screen_res")
-
-				baseQuery.Session(&gorm.Session{}).Raw(`
-					SELECT 
-						date(created_at) as date, 
-						count(*) as count,
-						sum(case when date(last_seen_at) = date(created_at) then 1 else 0 end) as new_count
-					FROM telemetry_records 
-					WHERE created_at > date('now', '-' || ? || ' days')
-					`+buildWhereClause(c)+`
-					GROUP BY date 
-					ORDER BY date ASC
-				`, days).Scan(&stats.GrowthData)
+				stats.ScreenStats = getDistribution("screen_bucket")
+				stats.OSBuildStats = getDistribution("os_name")
+				stats.ChannelStats = getDistribution("channel")
+				stats.SourceStats = getDistribution("COALESCE(NULLIF(source, ''), 'unknown')")
+				stats.GameStats = getDistribution("COALESCE(NULLIF(game_version, ''), 'unknown')")
+
+				baseQuery.Session(&gorm.Session{}).
+					Select(`date(created_at) as date, count(*) as count,
+						sum(case when date(last_seen_at) = date(created_at) then 1 else 0 end) as new_count`).
+					Where("created_at > date('now', '-' || ? || ' days')", days).
+					Group("date").Order("date ASC").
+					Scan(&stats.GrowthData)
 
 				var recentRecs []TelemetryRecord
-				baseQuery.Session(&gorm.Session{}).Order("last_seen_at desc").Limit(50).Find(&recentRecs)
+				baseQuery.Session(&gorm.Session{}).Order("last_seen_at desc").Limit(queryIntInRange(c, "recent_limit", recentUsersLimit, 1, 500)).Find(&recentRecs)
 
 				stats.RecentUsers = make([]map[string]any, len(recentRecs))
 				for i, r := range recentRecs {
@@ -127,8 +147,12 @@ func initRouter(r *gin.Engine) {
 						"os_build":          r.OSRelease,
 						"arch":              r.Arch,
 						"screen_resolution": r.ScreenRes,
+						"screen_bucket":     r.ScreenBucket,
+						"os_name":           r.OSName,
 						"python_version":    r.PythonVersion,
+						"game_version":      r.GameVersion,
 						"locale":            r.Locale,
+						"channel":           r.Channel,
 						"updated_at":        r.LastSeenAt.Format("2006-01-02 15:04:05"),
 						"created_at":        r.CreatedAt.Format("2006-01-02 15:04:05"),
 						"minutes_ago":       int(time.Since(r.LastSeenAt).Minutes()),
@@ -137,7 +161,7 @@ func initRouter(r *gin.Engine) {
 
 				getAllOptions := func(field string) []map[string]any {
 					var results []map[string]any
-					db.Model(&TelemetryRecord{}).Select(field + " as name, count(*) as value").
+					excludeBlocked(db.Model(&TelemetryRecord{})).Select(field + " as name, count(*) as value").
 						Group(field).Order("value desc").Scan(&results)
 					return results
 				}
@@ -145,10 +169,52 @@ func initRouter(r *gin.Engine) {
 				stats.ArchOptions = getAllOptions("arch")
 				stats.VersionOptions = getAllOptions("version")
 				stats.LocaleOptions = getAllOptions("locale")
+				stats.ChannelOptions = getAllOptions("channel")
 
+				setCachedStats(cacheKey, stats)
 				c.JSON(200, stats)
 			})
 
+			admin.GET("/users", func(c *gin.Context) {
+				limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+				offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+				if limit <= 0 || limit > 1000 {
+					limit = 100
+				}
+
+				var total int64
+				query := excludeBlocked(db.Model(&TelemetryRecord{}))
+				query.Count(&total)
+
+				var users []TelemetryRecord
+				query.Order("last_seen_at desc").Limit(limit).Offset(offset).Find(&users)
+				c.JSON(200, gin.H{"total": total, "items": users})
+			})
+
+			admin.GET("/sessions", func(c *gin.Context) {
+				days, _ := strconv.Atoi(c.DefaultQuery("range", "30"))
+				if days <= 0 {
+					days = 30
+				}
+				c.JSON(200, sessionStats(days))
+			})
+
+			admin.GET("/command-stats", func(c *gin.Context) {
+				days, _ := strconv.Atoi(c.DefaultQuery("range", "30"))
+				if days <= 0 {
+					days = 30
+				}
+				c.JSON(200, commandOutcomeStats(days))
+			})
+
+			admin.GET("/version-adoption", func(c *gin.Context) {
+				days, _ := strconv.Atoi(c.DefaultQuery("range", "90"))
+				if days <= 0 {
+					days = 90
+				}
+				c.JSON(200, versionAdoption(days))
+			})
+
 			admin.GET("/drilldown", func(c *gin.Context) {
 				dimension := c.Query("dimension")
 				value := c.Query("value")
@@ -156,13 +222,10 @@ func initRouter(r *gin.Engine) {
 				var resp DrilldownResponse
 				resp.Period = "当前筛选"
 
-				query := db.Model(&TelemetryRecord{})
-
-				if dimension != "" && value != "" && dimension != "date" {
-					query = query.Where(dimension+" = ?", value)
-				}
-				if dimension == "date" && value != "" {
-					query = query.Where("date(created_at) = ?", value)
+				query, ok := applyDrilldown(excludeBlocked(db.Model(&TelemetryRecord{})), dimension, value)
+				if !ok {
+					c.JSON(400, gin.H{"error": "Invalid dimension"})
+					return
 				}
 
 				var users []TelemetryRecord
@@ -180,44 +243,13 @@ func initRouter(r *gin.Engine) {
 			})
 
 			admin.GET("/export", func(c *gin.Context) {
-				c.Header("Content-Type", "text/csv")
-				c.Header("Content-Disposition", "attachment;filename=telemetry_export.csv")
-
-				writer := csv.NewWriter(c.Writer)
-				c.Writer.Write([]byte("\xEF\xBB\xBF"))
-
-				headers := []string{"Machine ID", "Version", "OS", "Arch", "Python", "Locale", "Screen", "First Seen", "Last Seen"}
-				writer.Write(headers)
-
-				var users []TelemetryRecord
-				startDate := c.Query("start_date")
-				endDate := c.Query("end_date")
-
-				query := db.Model(&TelemetryRecord{})
-				if startDate != "" {
-					query = query.Where("date(created_at) >= ?", startDate)
-				}
-				if endDate != "" {
-					query = query.Where("date(created_at) <= ?", endDate)
-				}
-
-				query.FindInBatches(&users, 1000, func(tx *gorm.DB, batch int) error {
-					for _, u := range users {
-						writer.Write([]string{
-							u.MachineID,
-							u.Version,
-							u.OS + " " + u.OSVersion,
-							u.Arch,
-							u.PythonVersion,
-							u.Locale,
-							u.ScreenRes,
-							u.CreatedAt.Format("2006-01-02 15:04:05"),
-							u.LastSeenAt.Format("2006-01-02 15:04:05"),
-						})
-					}
-					writer.Flush()
-					return nil
-				})
+				name := c.DefaultQuery("entity", "users")
+				entity, ok := exportEntities[name]
+				if !ok {
+					c.JSON(400, gin.H{"error": "Unknown entity"})
+					return
+				}
+				serveExport(c, name, entity)
 			})
 
 			admin.POST("/control", func(c *gin.Context) {
@@ -242,32 +274,76 @@ func initRouter(r *gin.Engine) {
 					}
 
 				case "alert":
-					if val, ok := req["alert_active"].(bool); ok {
-						sysConfig.AlertActive = val
+					announcement, err := saveAnnouncement("alert", req)
+					if errors.Is(err, errInvalidSchedule) {
+						c.JSON(400, gin.H{"error": "Invalid schedule time"})
+						return
 					}
-					if val, ok := req["title"].(string); ok {
-						sysConfig.AlertTitle = val
+					if err != nil {
+						c.JSON(500, gin.H{"error": "Save failed"})
+						return
 					}
-					if val, ok := req["content"].(string); ok {
-						sysConfig.AlertContent = val
+					c.JSON(200, gin.H{"status": "success", "config": sysConfig, "announcement": announcement})
+					return
+
+				case "notice":
+					announcement, err := saveAnnouncement("notice", req)
+					if errors.Is(err, errInvalidSchedule) {
+						c.JSON(400, gin.H{"error": "Invalid schedule time"})
+						return
 					}
-					if val, ok := req["scope"].(string); ok {
-						sysConfig.AlertScope = val
+					if err != nil {
+						c.JSON(500, gin.H{"error": "Save failed"})
+						return
+					}
+					if channel, _ := req["channel"].(string); channel != "" {
+						cfg := sysConfig.Channels[channel]
+						cfg.NoticeSet = true
+						cfg.NoticeActive = announcement.Active
+						setChannelConfig(channel, cfg)
 					}
+					c.JSON(200, gin.H{"status": "success", "config": sysConfig, "announcement": announcement})
+					return
 
-				case "notice":
-					if val, ok := req["notice_active"].(bool); ok {
-						sysConfig.NoticeActive = val
+				case "compat":
+					if val, ok := req["min_version"].(string); ok {
+						sysConfig.MinVersion = val
 					}
-					if val, ok := req["content"].(string); ok {
-						sysConfig.NoticeContent = val
+					if val, ok := req["message"].(string); ok {
+						sysConfig.MinVersionMsg = val
 					}
-					if val, ok := req["scope"].(string); ok {
-						sysConfig.NoticeScope = val
+					if val, ok := req["force_update"].(bool); ok {
+						sysConfig.ForceUpdate = val
+					}
+					if val, ok := req["unsafe_versions"].(string); ok {
+						sysConfig.UnsafeVersions = val
+					}
+					if val, ok := req["unsafe_message"].(string); ok {
+						sysConfig.UnsafeVersionMsg = val
 					}
 
 				case "update":
+					if channel, _ := req["channel"].(string); channel != "" {
+						cfg := sysConfig.Channels[channel]
+						cfg.UpdateSet = true
+						cfg.UpdateActive = true
+						if val, ok := req["update_active"].(bool); ok {
+							cfg.UpdateActive = val
+						}
+						if val, ok := req["content"].(string); ok {
+							cfg.UpdateContent = val
+						}
+						if val, ok := req["url"].(string); ok {
+							cfg.UpdateUrl = val
+						}
+						setChannelConfig(channel, cfg)
+						recordAnnouncement("update", req)
+						break
+					}
 					sysConfig.UpdateActive = true
+					if val, ok := req["update_active"].(bool); ok {
+						sysConfig.UpdateActive = val
+					}
 					if val, ok := req["content"].(string); ok {
 						sysConfig.UpdateContent = val
 					}
@@ -277,11 +353,301 @@ func initRouter(r *gin.Engine) {
 					if val, ok := req["scope"].(string); ok {
 						sysConfig.UpdateScope = val
 					}
+					recordAnnouncement("update", req)
 				}
 
 				c.JSON(200, gin.H{"status": "success", "config": sysConfig})
 			})
 
+			admin.GET("/backup", func(c *gin.Context) {
+				c.JSON(200, gin.H{"dir": backupDir, "keep": backupKeep, "backups": listBackups()})
+			})
+
+			admin.POST("/backup", func(c *gin.Context) {
+				name, err := runBackup()
+				if err != nil {
+					c.JSON(500, gin.H{"error": "Backup failed"})
+					return
+				}
+				c.JSON(200, gin.H{"status": "success", "file": name})
+			})
+
+			admin.POST("/purge", func(c *gin.Context) {
+				var req struct {
+					Days int `json:"days"`
+				}
+				if err := c.ShouldBindJSON(&req); err != nil || req.Days <= 0 {
+					c.JSON(400, gin.H{"error": "Invalid JSON"})
+					return
+				}
+
+				records, logs, err := purgeInactive(req.Days)
+				if err != nil {
+					c.JSON(500, gin.H{"error": "Purge failed"})
+					return
+				}
+				c.JSON(200, gin.H{"status": "success", "records": records, "logs": logs})
+			})
+
+			admin.GET("/blocklist", func(c *gin.Context) {
+				var entries []BlockEntry
+				db.Order("created_at desc").Find(&entries)
+				c.JSON(200, gin.H{"items": entries})
+			})
+
+			admin.POST("/block", func(c *gin.Context) {
+				var req struct {
+					Kind         string `json:"kind"`
+					Value        string `json:"value"`
+					Reason       string `json:"reason"`
+					ExpiresHours int    `json:"expires_hours"` // 0 表示永久
+				}
+				if err := c.ShouldBindJSON(&req); err != nil {
+					c.JSON(400, gin.H{"error": "Invalid JSON"})
+					return
+				}
+				if (req.Kind != BlockKindIP && req.Kind != BlockKindMachineID) || req.Value == "" {
+					c.JSON(400, gin.H{"error": "Invalid block entry"})
+					return
+				}
+
+				entry, err := addBlock(req.Kind, req.Value, req.Reason, req.ExpiresHours)
+				if err != nil {
+					c.JSON(500, gin.H{"error": "Block failed"})
+					return
+				}
+				c.JSON(200, gin.H{"status": "success", "entry": entry})
+			})
+
+			admin.POST("/unblock", func(c *gin.Context) {
+				var req struct {
+					ID uint `json:"id"`
+				}
+				if err := c.ShouldBindJSON(&req); err != nil {
+					c.JSON(400, gin.H{"error": "Invalid JSON"})
+					return
+				}
+
+				if err := db.Delete(&BlockEntry{}, req.ID).Error; err != nil {
+					c.JSON(500, gin.H{"error": "Unblock failed"})
+					return
+				}
+				c.JSON(200, gin.H{"status": "success"})
+			})
+
+			admin.GET("/announcements", func(c *gin.Context) {
+				var items []Announcement
+				query := db.Order("created_at desc").Limit(200)
+				if kind := c.Query("kind"); kind != "" {
+					query = query.Where("kind = ?", kind)
+				}
+				query.Find(&items)
+				fillReceiptCounts(items)
+				c.JSON(200, gin.H{"items": items})
+			})
+
+			admin.GET("/flags", func(c *gin.Context) {
+				var flags []FeatureFlag
+				db.Order("name asc").Find(&flags)
+				c.JSON(200, gin.H{"items": flags})
+			})
+
+			admin.POST("/flag", func(c *gin.Context) {
+				var req FeatureFlag
+				if err := c.ShouldBindJSON(&req); err != nil || req.Name == "" {
+					c.JSON(400, gin.H{"error": "Invalid JSON"})
+					return
+				}
+				if req.Percentage < 0 || req.Percentage > 100 {
+					c.JSON(400, gin.H{"error": "Invalid percentage"})
+					return
+				}
+
+				flag := FeatureFlag{Name: req.Name}
+				err := db.Where(FeatureFlag{Name: req.Name}).
+					Assign(map[string]any{
+						"description": req.Description,
+						"enabled":     req.Enabled,
+						"versions":    req.Versions,
+						"locales":     req.Locales,
+						"percentage":  req.Percentage,
+					}).FirstOrCreate(&flag).Error
+				if err != nil {
+					c.JSON(500, gin.H{"error": "Update failed"})
+					return
+				}
+				c.JSON(200, gin.H{"status": "success", "flag": flag})
+			})
+
+			admin.POST("/delete-flag", func(c *gin.Context) {
+				var req struct {
+					Name string `json:"name"`
+				}
+				if err := c.ShouldBindJSON(&req); err != nil {
+					c.JSON(400, gin.H{"error": "Invalid JSON"})
+					return
+				}
+
+				if err := db.Delete(&FeatureFlag{}, "name = ?", req.Name).Error; err != nil {
+					c.JSON(500, gin.H{"error": "Delete failed"})
+					return
+				}
+				c.JSON(200, gin.H{"status": "success"})
+			})
+
+			admin.GET("/experiments", func(c *gin.Context) {
+				var experiments []Experiment
+				db.Order("created_at desc").Find(&experiments)
+				c.JSON(200, gin.H{"items": experiments})
+			})
+
+			admin.POST("/experiment", func(c *gin.Context) {
+				var req Experiment
+				if err := c.ShouldBindJSON(&req); err != nil || req.Name == "" || len(req.variantList()) == 0 {
+					c.JSON(400, gin.H{"error": "Invalid JSON"})
+					return
+				}
+				if req.Percentage < 0 || req.Percentage > 100 {
+					c.JSON(400, gin.H{"error": "Invalid percentage"})
+					return
+				}
+
+				experiment := Experiment{Name: req.Name}
+				err := db.Where(Experiment{Name: req.Name}).
+					Assign(map[string]any{
+						"description": req.Description,
+						"variants":    req.Variants,
+						"percentage":  req.Percentage,
+						"active":      req.Active,
+					}).FirstOrCreate(&experiment).Error
+				if err != nil {
+					c.JSON(500, gin.H{"error": "Update failed"})
+					return
+				}
+				c.JSON(200, gin.H{"status": "success", "experiment": experiment})
+			})
+
+			admin.POST("/delete-experiment", func(c *gin.Context) {
+				var req struct {
+					Name string `json:"name"`
+				}
+				if err := c.ShouldBindJSON(&req); err != nil {
+					c.JSON(400, gin.H{"error": "Invalid JSON"})
+					return
+				}
+
+				if err := db.Delete(&Experiment{}, "name = ?", req.Name).Error; err != nil {
+					c.JSON(500, gin.H{"error": "Delete failed"})
+					return
+				}
+				c.JSON(200, gin.H{"status": "success"})
+			})
+
+			admin.GET("/experiment-results", func(c *gin.Context) {
+				c.JSON(200, gin.H{"name": c.Query("name"), "items": experimentResults(c.Query("name"))})
+			})
+
+			admin.GET("/anomalies", func(c *gin.Context) {
+				var events []AnomalyEvent
+				query := db.Order("created_at desc").Limit(100)
+				if c.Query("all") == "" {
+					query = query.Where("acknowledged = ?", false)
+				}
+				query.Find(&events)
+				c.JSON(200, gin.H{"items": events})
+			})
+
+			admin.POST("/ack-anomaly", func(c *gin.Context) {
+				var req struct {
+					ID uint `json:"id"`
+				}
+				if err := c.ShouldBindJSON(&req); err != nil {
+					c.JSON(400, gin.H{"error": "Invalid JSON"})
+					return
+				}
+
+				if err := db.Model(&AnomalyEvent{}).Where("id = ?", req.ID).Update("acknowledged", true).Error; err != nil {
+					c.JSON(500, gin.H{"error": "Update failed"})
+					return
+				}
+				c.JSON(200, gin.H{"status": "success"})
+			})
+
+			admin.GET("/logs", func(c *gin.Context) {
+				var logs []ClientLog
+				query := db.Order("created_at desc").Limit(200)
+				if level := c.Query("level"); level != "" {
+					query = query.Where("level = ?", level)
+				}
+				if version := c.Query("version"); version != "" {
+					query = query.Where("version = ?", version)
+				}
+				if machineID := c.Query("machine_id"); machineID != "" {
+					query = query.Where("machine_id = ?", machineID)
+				}
+				query.Find(&logs)
+				c.JSON(200, gin.H{"items": logs})
+			})
+
+			admin.GET("/feedback", func(c *gin.Context) {
+				limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+				offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+				if limit <= 0 || limit > 1000 {
+					limit = 100
+				}
+
+				query := db.Model(&Feedback{})
+				if category := c.Query("category"); category != "" {
+					query = query.Where("category = ?", category)
+				}
+				if status := c.Query("status"); status != "" {
+					query = query.Where("status = ?", status)
+				}
+				if version := c.Query("version"); version != "" {
+					query = query.Where("version = ?", version)
+				}
+
+				var total int64
+				query.Count(&total)
+
+				var items []Feedback
+				query.Omit("diagnostics").Order("created_at desc").Limit(limit).Offset(offset).Find(&items)
+				c.JSON(200, gin.H{"total": total, "items": items})
+			})
+
+			admin.GET("/feedback-diagnostics", func(c *gin.Context) {
+				var item Feedback
+				if err := db.First(&item, c.Query("id")).Error; err != nil {
+					c.JSON(404, gin.H{"error": "Not found"})
+					return
+				}
+				c.String(200, item.Diagnostics)
+			})
+
+			admin.POST("/feedback-status", func(c *gin.Context) {
+				var req struct {
+					ID     uint   `json:"id"`
+					Status string `json:"status"`
+				}
+				if err := c.ShouldBindJSON(&req); err != nil || (req.Status != "open" && req.Status != "resolved") {
+					c.JSON(400, gin.H{"error": "Invalid JSON"})
+					return
+				}
+				if err := db.Model(&Feedback{}).Where("id = ?", req.ID).Update("status", req.Status).Error; err != nil {
+					c.JSON(500, gin.H{"error": "Update failed"})
+					return
+				}
+				c.JSON(200, gin.H{"status": "success"})
+			})
+
+			admin.GET("/log-stats", func(c *gin.Context) {
+				days, _ := strconv.Atoi(c.DefaultQuery("range", "7"))
+				if days <= 0 {
+					days = 7
+				}
+				c.JSON(200, gin.H{"items": logStatsByVersion(days)})
+			})
+
 			admin.POST("/update-alias", func(c *gin.Context) {
 				var req struct {
 					MachineID string `json:"machine_id"`
@@ -301,13 +667,23 @@ func initRouter(r *gin.Engine) {
 
 			admin.POST("/user-command", func(c *gin.Context) {
 				var req struct {
-					MachineID string `json:"machine_id"`
-					Command   string `json:"command"` // JSON string
+					MachineID string            `json:"machine_id"`
+					Command   string            `json:"command"` // JSON string
+					Template  string            `json:"template"`
+					Params    map[string]string `json:"params"`
 				}
 				if err := c.ShouldBindJSON(&req); err != nil {
 					c.JSON(400, gin.H{"error": "Invalid JSON"})
 					return
 				}
+				if req.Template != "" {
+					cmd, err := renderCommand(req.Template, req.Params)
+					if err != nil {
+						c.JSON(400, gin.H{"error": err.Error()})
+						return
+					}
+					req.Command = cmd
+				}
 
 				err := db.Model(&TelemetryRecord{}).Where("machine_id = ?", req.MachineID).Update("pending_command", req.Command).Error
 				if err != nil {
@@ -317,6 +693,105 @@ func initRouter(r *gin.Engine) {
 				c.JSON(200, gin.H{"status": "success"})
 			})
 
+			admin.GET("/command-templates", func(c *gin.Context) {
+				c.JSON(200, gin.H{"items": commandTemplates})
+			})
+
+			admin.POST("/bulk-command", func(c *gin.Context) {
+				var req struct {
+					Filter   UserFilter        `json:"filter"`
+					Segment  string            `json:"segment"`
+					Command  string            `json:"command"`
+					Template string            `json:"template"`
+					Params   map[string]string `json:"params"`
+					Token    string            `json:"token"` // 为空时仅预览, 返回命中数量与确认令牌
+				}
+				if err := c.ShouldBindJSON(&req); err != nil {
+					c.JSON(400, gin.H{"error": "Invalid JSON"})
+					return
+				}
+				cmd, err := resolveCommand(req.Template, req.Params, req.Command)
+				if err != nil {
+					c.JSON(400, gin.H{"error": err.Error()})
+					return
+				}
+				req.Command = cmd
+				if req.Segment != "" {
+					filter, err := loadSegmentFilter(req.Segment)
+					if err != nil {
+						c.JSON(400, gin.H{"error": "Segment not found"})
+						return
+					}
+					req.Filter = filter
+				}
+
+				if req.Token == "" {
+					token, count := previewBulkCommand(req.Filter, req.Command)
+					c.JSON(200, gin.H{"status": "preview", "count": count, "token": token})
+					return
+				}
+
+				affected, err := executeBulkCommand(req.Token, req.Filter, req.Command)
+				if err != nil {
+					c.JSON(400, gin.H{"error": err.Error()})
+					return
+				}
+				c.JSON(200, gin.H{"status": "success", "affected": affected})
+			})
+
+			admin.GET("/segments", func(c *gin.Context) {
+				var segments []Segment
+				db.Order("name asc").Find(&segments)
+				c.JSON(200, gin.H{"items": segments})
+			})
+
+			admin.POST("/segment", func(c *gin.Context) {
+				var req struct {
+					Name   string     `json:"name"`
+					Filter UserFilter `json:"filter"`
+				}
+				if err := c.ShouldBindJSON(&req); err != nil || req.Name == "" {
+					c.JSON(400, gin.H{"error": "Invalid JSON"})
+					return
+				}
+				seg, err := saveSegment(req.Name, req.Filter)
+				if err != nil {
+					c.JSON(500, gin.H{"error": "Save failed"})
+					return
+				}
+				c.JSON(200, gin.H{"status": "success", "segment": seg})
+			})
+
+			admin.GET("/duplicates", func(c *gin.Context) {
+				c.JSON(200, gin.H{"groups": findDuplicates()})
+			})
+
+			admin.POST("/merge-users", func(c *gin.Context) {
+				var req struct {
+					Target  string   `json:"target"`
+					Sources []string `json:"sources"`
+				}
+				if err := c.ShouldBindJSON(&req); err != nil || req.Target == "" || len(req.Sources) == 0 {
+					c.JSON(400, gin.H{"error": "Invalid JSON"})
+					return
+				}
+
+				merged, err := mergeRecords(req.Target, req.Sources)
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					c.JSON(404, gin.H{"error": "Not found"})
+					return
+				}
+				if errors.Is(err, errNoMergeSources) {
+					c.JSON(400, gin.H{"error": "No source records"})
+					return
+				}
+				if err != nil {
+					c.JSON(500, gin.H{"error": "Merge failed"})
+					return
+				}
+				c.JSON(200, gin.H{"status": "success", "record": merged})
+			})
+
 			admin.POST("/delete-user", func(c *gin.Context) {
 				var req struct {
 					MachineID string `json:"machine_id"`
@@ -330,64 +805,486 @@ func initRouter(r *gin.Engine) {
 					c.JSON(500, gin.H{"error": "Delete failed"})
 					return
 				}
+				c.JSON(200, gin.H{"status": "success", "retention_days": deletedRetentionDays})
+			})
+
+			admin.GET("/deleted-users", func(c *gin.Context) {
+				c.JSON(200, gin.H{"items": listDeletedUsers(), "retention_days": deletedRetentionDays})
+			})
+
+			admin.POST("/restore-user", func(c *gin.Context) {
+				var req struct {
+					MachineID string `json:"machine_id"`
+				}
+				if err := c.ShouldBindJSON(&req); err != nil {
+					c.JSON(400, gin.H{"error": "Invalid JSON"})
+					return
+				}
+				restored, err := restoreUser(req.MachineID)
+				if err != nil {
+					c.JSON(500, gin.H{"error": "Restore failed"})
+					return
+				}
+				if restored == 0 {
+					c.JSON(404, gin.H{"error": "Not found"})
+					return
+				}
+				c.JSON(200, gin.H{"status": "success"})
+			})
+
+			admin.GET("/downloads", func(c *gin.Context) {
+				var links []DownloadLink
+				db.Order("updated_at desc").Find(&links)
+				c.JSON(200, gin.H{"items": links})
+			})
+
+			admin.POST("/download", func(c *gin.Context) {
+				var req struct {
+					Name    string `json:"name"`
+					Version string `json:"version"`
+					Url     string `json:"url"`
+				}
+				if err := c.ShouldBindJSON(&req); err != nil {
+					c.JSON(400, gin.H{"error": "Invalid JSON"})
+					return
+				}
+				link, err := saveDownloadLink(req.Name, req.Version, req.Url)
+				if err != nil {
+					c.JSON(400, gin.H{"error": err.Error()})
+					return
+				}
+				c.JSON(200, gin.H{"status": "success", "link": link})
+			})
+
+			admin.GET("/download-stats", func(c *gin.Context) {
+				days, _ := strconv.Atoi(c.DefaultQuery("range", "30"))
+				if days <= 0 {
+					days = 30
+				}
+				c.JSON(200, downloadStats(days))
+			})
+
+			admin.GET("/authors", func(c *gin.Context) {
+				var authors []PackAuthor
+				db.Order("created_at desc").Find(&authors)
+				c.JSON(200, gin.H{"items": authors})
+			})
+
+			admin.POST("/author", func(c *gin.Context) {
+				var req struct {
+					Name string `json:"name"`
+				}
+				if err := c.ShouldBindJSON(&req); err != nil || req.Name == "" {
+					c.JSON(400, gin.H{"error": "Invalid JSON"})
+					return
+				}
+				author, token, err := createPackAuthor(req.Name)
+				if err != nil {
+					c.JSON(500, gin.H{"error": "Create failed"})
+					return
+				}
+				// 令牌只在创建时返回一次
+				c.JSON(200, gin.H{"status": "success", "author": author, "token": token})
+			})
+
+			admin.POST("/revoke-author", func(c *gin.Context) {
+				var req struct {
+					ID uint `json:"id"`
+				}
+				if err := c.ShouldBindJSON(&req); err != nil {
+					c.JSON(400, gin.H{"error": "Invalid JSON"})
+					return
+				}
+				if err := db.Model(&PackAuthor{}).Where("id = ?", req.ID).Update("active", false).Error; err != nil {
+					c.JSON(500, gin.H{"error": "Update failed"})
+					return
+				}
 				c.JSON(200, gin.H{"status": "success"})
 			})
 		}
 	}
 
+	author := r.Group("/author", func(c *gin.Context) {
+		if !limitBody(c, maxAuthorBodyBytes) {
+			return
+		}
+		c.Next()
+	}, authorAuthMiddleware)
+	{
+		author.GET("/packs", func(c *gin.Context) {
+			current := c.MustGet("author").(PackAuthor)
+			var packs []RepoPack
+			db.Where("author_id = ?", current.ID).Order("updated_at desc").Find(&packs)
+			c.JSON(200, gin.H{"items": packs})
+		})
+
+		author.POST("/pack", func(c *gin.Context) {
+			var req PackPublishRequest
+			if err := c.ShouldBindJSON(&req); err != nil {
+				bindError(c, err)
+				return
+			}
+			if err := req.validate(); err != nil {
+				c.JSON(400, gin.H{"error": err.Error()})
+				return
+			}
+			pack, err := publishPack(c.MustGet("author").(PackAuthor), req)
+			if err != nil {
+				c.JSON(403, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(200, gin.H{"status": "success", "pack": pack})
+		})
+
+		author.POST("/delete-pack", func(c *gin.Context) {
+			var req struct {
+				Name string `json:"name"`
+			}
+			if err := c.ShouldBindJSON(&req); err != nil {
+				bindError(c, err)
+				return
+			}
+			current := c.MustGet("author").(PackAuthor)
+			result := db.Where("name = ? AND author_id = ?", req.Name, current.ID).Delete(&RepoPack{})
+			if result.Error != nil {
+				c.JSON(500, gin.H{"error": "Delete failed"})
+				return
+			}
+			c.JSON(200, gin.H{"status": "success", "deleted": result.RowsAffected})
+		})
+	}
+
+	r.GET("/download/:name", func(c *gin.Context) {
+		var link DownloadLink
+		if err := db.Where("name = ?", c.Param("name")).First(&link).Error; err != nil {
+			c.JSON(404, gin.H{"error": "Not found"})
+			return
+		}
+		recordDownload(link, c.ClientIP(), c.Query("m"))
+		c.Redirect(http.StatusFound, link.Url)
+	})
+
+	r.GET("/repository/index", func(c *gin.Context) {
+		c.JSON(200, gin.H{"packs": repositoryIndex()})
+	})
+
+	r.POST("/feedback", func(c *gin.Context) {
+		if isBlocked(BlockKindIP, c.ClientIP()) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access Denied"})
+			return
+		}
+
+		var req FeedbackRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			bindError(c, err)
+			return
+		}
+		if isBlocked(BlockKindMachineID, req.MachineID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access Denied"})
+			return
+		}
+		item, ok := req.toFeedback()
+		if !ok {
+			c.JSON(400, gin.H{"error": "Empty feedback"})
+			return
+		}
+		if err := db.Create(&item).Error; err != nil {
+			c.JSON(500, gin.H{"status": "error"})
+			return
+		}
+		c.JSON(200, gin.H{"status": "success", "id": item.ID})
+	})
+
+	r.POST("/logs", func(c *gin.Context) {
+		if isBlocked(BlockKindIP, c.ClientIP()) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access Denied"})
+			return
+		}
+
+		var batch LogBatch
+		if err := c.ShouldBindJSON(&batch); err != nil {
+			bindError(c, err)
+			return
+		}
+		if err := batch.validate(); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		if isBlocked(BlockKindMachineID, batch.MachineID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access Denied"})
+			return
+		}
+
+		logs := batch.acceptedLogs()
+		if len(logs) > 0 {
+			if err := db.Create(&logs).Error; err != nil {
+				c.JSON(500, gin.H{"status": "error"})
+				return
+			}
+		}
+		c.JSON(200, gin.H{"status": "success", "accepted": len(logs)})
+	})
+
+	r.POST("/ack", func(c *gin.Context) {
+		if isBlocked(BlockKindIP, c.ClientIP()) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access Denied"})
+			return
+		}
+
+		var req struct {
+			MachineID      string `json:"machine_id"`
+			AnnouncementID uint   `json:"announcement_id"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil || req.MachineID == "" || req.AnnouncementID == 0 {
+			bindError(c, err)
+			return
+		}
+		if isBlocked(BlockKindMachineID, req.MachineID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access Denied"})
+			return
+		}
+
+		if err := markRead(req.AnnouncementID, req.MachineID); err != nil {
+			c.JSON(500, gin.H{"status": "error"})
+			return
+		}
+		c.JSON(200, gin.H{"status": "success"})
+	})
+
+	r.POST("/command-ack", func(c *gin.Context) {
+		if isBlocked(BlockKindIP, c.ClientIP()) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access Denied"})
+			return
+		}
+
+		var req struct {
+			MachineID string `json:"machine_id"`
+			CommandID uint   `json:"command_id"`
+			Status    string `json:"status"`
+			Error     string `json:"error"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil || req.MachineID == "" || req.CommandID == 0 {
+			bindError(c, err)
+			return
+		}
+		if isBlocked(BlockKindMachineID, req.MachineID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access Denied"})
+			return
+		}
+
+		if req.Status != CommandStatusExecuted && req.Status != CommandStatusFailed {
+			c.JSON(400, gin.H{"error": "Invalid status"})
+			return
+		}
+
+		if err := ackCommand(req.CommandID, req.MachineID, req.Status, req.Error); err != nil {
+			c.JSON(500, gin.H{"status": "error"})
+			return
+		}
+		c.JSON(200, gin.H{"status": "success"})
+	})
+
+	r.POST("/experiment-event", func(c *gin.Context) {
+		if isBlocked(BlockKindIP, c.ClientIP()) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access Denied"})
+			return
+		}
+
+		var req struct {
+			MachineID  string `json:"machine_id"`
+			Experiment string `json:"experiment"`
+			Kind       string `json:"kind"`
+			Goal       string `json:"goal"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil || req.MachineID == "" {
+			bindError(c, err)
+			return
+		}
+		if isBlocked(BlockKindMachineID, req.MachineID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access Denied"})
+			return
+		}
+		if req.Kind != ExperimentEventExposure && req.Kind != ExperimentEventConversion {
+			c.JSON(400, gin.H{"error": "Invalid event kind"})
+			return
+		}
+
+		// 分组以服务端计算为准, 不信任客户端上报的分组
+		var experiment Experiment
+		if err := db.Where("name = ?", req.Experiment).First(&experiment).Error; err != nil {
+			c.JSON(404, gin.H{"error": "Experiment not found"})
+			return
+		}
+		variant := experiment.assign(req.MachineID)
+		if variant == "" {
+			c.JSON(200, gin.H{"status": "ignored"})
+			return
+		}
+
+		err := db.Create(&ExperimentEvent{
+			Experiment: experiment.Name,
+			Variant:    variant,
+			MachineID:  req.MachineID,
+			Kind:       req.Kind,
+			Goal:       req.Goal,
+		}).Error
+		if err != nil {
+			c.JSON(500, gin.H{"status": "error"})
+			return
+		}
+		c.JSON(200, gin.H{"status": "success", "variant": variant})
+	})
+
+	r.POST("/session-event", func(c *gin.Context) {
+		if isBlocked(BlockKindIP, c.ClientIP()) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access Denied"})
+			return
+		}
+
+		var req struct {
+			MachineID string `json:"machine_id"`
+			SessionID int    `json:"session_id"`
+			Version   string `json:"version"`
+			Event     string `json:"event"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil || req.MachineID == "" || req.SessionID == 0 {
+			bindError(c, err)
+			return
+		}
+		if isBlocked(BlockKindMachineID, req.MachineID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access Denied"})
+			return
+		}
+
+		var err error
+		switch req.Event {
+		case SessionEventStart:
+			touchSession(TelemetryRecord{MachineID: req.MachineID, SessionID: req.SessionID, Version: req.Version})
+		case SessionEventEnd:
+			err = endSession(req.MachineID, req.SessionID)
+		default:
+			c.JSON(400, gin.H{"error": "Invalid event"})
+			return
+		}
+		if err != nil {
+			c.JSON(500, gin.H{"status": "error"})
+			return
+		}
+		c.JSON(200, gin.H{"status": "success"})
+	})
+
 	r.POST("/telemetry", func(c *gin.Context) {
 		if sysConfig.Maintenance && sysConfig.StopNewData {
-			c.JSON(503, gin.H{"status": "maintenance", "sys_config": sysConfig})
+			// 维护期间只下发维护状态, 不暴露公告、通道配置等其他字段
+			c.JSON(503, gin.H{"status": "maintenance", "sys_config": gin.H{
+				"maintenance":     true,
+				"maintenance_msg": sysConfig.MaintenanceMsg,
+			}})
+			return
+		}
+
+		if isBlocked(BlockKindIP, c.ClientIP()) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access Denied"})
 			return
 		}
 
 		var record TelemetryRecord
 		if err := c.ShouldBindJSON(&record); err != nil {
-			c.JSON(400, gin.H{"error": "Invalid JSON"})
+			bindError(c, err)
+			return
+		}
+
+		if err := validateRecord(&record); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+
+		if isBlocked(BlockKindMachineID, record.MachineID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access Denied"})
 			return
 		}
 
+		if record.Channel == "" {
+			record.Channel = "stable"
+		}
 		record.LastSeenAt = time.Now()
+		record.LastIP = c.ClientIP()
+		normalizeRecord(&record)
 
+		updates := clause.AssignmentColumns([]string{
+			"version", "os", "os_release", "os_version", "arch",
+			"cpu_count", "screen_res", "python_version", "locale", "session_id", "last_seen_at", "last_ip", "channel",
+			"screen_bucket", "os_name",
+		})
+		// 重新上报的机器自动恢复软删除, 不信任客户端传入的 deleted_at
+		updates = append(updates, clause.Assignment{
+			Column: clause.Column{Name: "deleted_at"},
+			Value:  nil,
+		})
+		// 安装来源只记录首次上报的值
+		updates = append(updates, clause.Assignment{
+			Column: clause.Column{Name: "source"},
+			Value:  gorm.Expr("COALESCE(NULLIF(telemetry_records.source, ''), excluded.source)"),
+		})
+		// 客户端未设置游戏路径时不上报游戏版本, 保留最近一次识别到的值
+		updates = append(updates, clause.Assignment{
+			Column: clause.Column{Name: "game_version"},
+			Value:  gorm.Expr("COALESCE(NULLIF(excluded.game_version, ''), telemetry_records.game_version)"),
+		})
 		err := db.Clauses(clause.OnConflict{
-			Columns: []clause.Column{{Name: "machine_id"}},
-			DoUpdates: clause.AssignmentColumns([]string{
-				"version", "os", "os_release", "os_version", "arch",
-				"cpu_count", "screen_res", "python_version", "locale", "session_id", "last_seen_at",
-			}),
+			Columns:   []clause.Column{{Name: "machine_id"}},
+			DoUpdates: updates,
 		}).Create(&record).Error
 
 		if err != nil {
 			c.JSON(500, gin.H{"status": "error"})
 			return
 		}
+		// 只统计成功入库的上报, 被拒绝或封禁的请求不计入异常检测的基线
+		ingestCounter.Add(1)
+		touchSession(record)
 
 		clientConfig := sysConfig
-		if sysConfig.AlertScope != "all" && sysConfig.AlertScope != record.Version {
-			clientConfig.AlertActive = false
-			clientConfig.AlertTitle = ""
-			clientConfig.AlertContent = ""
+		if alert, ok := activeAnnouncement("alert", record); ok {
+			clientConfig.AlertActive = true
+			clientConfig.AlertID = alert.ID
+			clientConfig.AlertTitle = alert.Title
+			clientConfig.AlertContent = alert.Content
 		}
-		if sysConfig.NoticeScope != "all" && sysConfig.NoticeScope != record.Version {
-			clientConfig.NoticeActive = false
-			clientConfig.NoticeContent = ""
+		if notice, ok := activeAnnouncement("notice", record); ok {
+			clientConfig.NoticeActive = true
+			clientConfig.NoticeID = notice.ID
+			clientConfig.NoticeContent = notice.Content
 		}
 		if sysConfig.UpdateScope != "all" && sysConfig.UpdateScope != record.Version {
 			clientConfig.UpdateActive = false
 			clientConfig.UpdateContent = ""
 			clientConfig.UpdateUrl = ""
 		}
+		applyChannelConfig(&clientConfig, record.Channel)
+		if clientConfig.AlertActive {
+			recordDelivery(clientConfig.AlertID, record.MachineID)
+		}
+		if clientConfig.NoticeActive {
+			recordDelivery(clientConfig.NoticeID, record.MachineID)
+		}
 
 		var pendingCmd string
+		var commandID uint
 		db.Model(&TelemetryRecord{}).Where("machine_id = ?", record.MachineID).Select("pending_command").Scan(&pendingCmd)
 		if pendingCmd != "" {
 			db.Model(&TelemetryRecord{}).Where("machine_id = ?", record.MachineID).Update("pending_command", "")
+			commandID = recordCommandDelivery(record, pendingCmd)
 		}
 
 		c.JSON(200, gin.H{
 			"status":       "success",
 			"sys_config":   clientConfig,
 			"user_command": pendingCmd,
+			"command_id":   commandID,
+			"compat":       buildCompatPolicy(record.Version),
+			"features":     evaluateFlags(record),
+			"experiments":  assignExperiments(record.MachineID),
 		})
 	})
 }
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 演示数据的机器码统一带此前缀, 便于识别和清理
+const demoMachinePrefix = "demo-"
+
+type demoWeighted struct {
+	value  string
+	weight int
+}
+
+func weightedIndex(rng *rand.Rand, weights []int) int {
+	total := 0
+	for _, w := range weights {
+		total += w
+	}
+	n := rng.Intn(total)
+	for i, w := range weights {
+		if n < w {
+			return i
+		}
+		n -= w
+	}
+	return 0
+}
+
+func pickWeighted(rng *rand.Rand, items []demoWeighted) string {
+	weights := make([]int, len(items))
+	for i, it := range items {
+		weights[i] = it.weight
+	}
+	return items[weightedIndex(rng, weights)].value
+}
+
+var (
+	demoVersions = []demoWeighted{{"2.4.0", 10}, {"2.5.0", 25}, {"2.5.1", 40}, {"2.6.0-beta.1", 8}}
+	demoLocales  = []demoWeighted{{"zh-CN", 60}, {"zh-TW", 10}, {"en-US", 15}, {"ru-RU", 10}, {"ja-JP", 5}}
+	demoScreens  = []demoWeighted{{"1920x1080", 50}, {"2560x1440", 30}, {"3840x2160", 10}, {"1366x768", 10}}
+	demoChannels = []demoWeighted{{"stable", 85}, {"beta", 15}}
+	demoSources  = []demoWeighted{{"github", 40}, {"bilibili", 35}, {"wtlive", 15}, {"", 10}}
+	demoGames    = []demoWeighted{{"2.41.0.37", 55}, {"2.39.0.102", 30}, {"2.37.0.66", 5}, {"", 10}}
+	demoOS       = []struct {
+		os, release, version string
+		weight               int
+	}{
+		{"Windows", "10", "10.0.19045", 35},
+		{"Windows", "10", "10.0.22631", 55},
+		{"Linux", "6.8.0", "#1 SMP", 7},
+		{"Darwin", "23.4.0", "Darwin Kernel Version 23.4.0", 3},
+	}
+	demoLogMessages = []string{"安装失败: 权限不足", "解压失败: 压缩包已损坏", "读取 config.blk 失败", "网络请求超时"}
+)
+
+// seedDemoData 生成跨越 days 天的模拟用户、会话、日志、反馈和下载记录, 仅用于开发与演示
+func seedDemoData(users, days int) error {
+	if users <= 0 || days <= 0 {
+		return fmt.Errorf("users 和 days 必须大于 0")
+	}
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	now := time.Now()
+
+	osWeights := make([]int, len(demoOS))
+	for i, o := range demoOS {
+		osWeights[i] = o.weight
+	}
+
+	var records []TelemetryRecord
+	var sessions []SessionRecord
+	var logs []ClientLog
+	var feedback []Feedback
+	var downloads []DownloadEvent
+
+	for i := 0; i < users; i++ {
+		o := demoOS[weightedIndex(rng, osWeights)]
+
+		createdAt := now.Add(-time.Duration(rng.Int63n(int64(days) * int64(24*time.Hour))))
+		// 约三成用户在近一天内仍然活跃, 其余在注册后随机一段时间内流失
+		lastSeen := createdAt.Add(time.Duration(rng.Int63n(int64(now.Sub(createdAt)) + 1)))
+		if rng.Intn(10) < 3 {
+			lastSeen = now.Add(-time.Duration(rng.Intn(24*60)) * time.Minute)
+		}
+		if lastSeen.Before(createdAt) {
+			lastSeen = createdAt
+		}
+
+		record := TelemetryRecord{
+			MachineID:     fmt.Sprintf("%s%06d", demoMachinePrefix, i),
+			Version:       pickWeighted(rng, demoVersions),
+			OS:            o.os,
+			OSRelease:     o.release,
+			OSVersion:     o.version,
+			Arch:          "AMD64",
+			CPUCount:      []int{4, 6, 8, 12, 16}[rng.Intn(5)],
+			ScreenRes:     pickWeighted(rng, demoScreens),
+			PythonVersion: "3.12.4",
+			Locale:        pickWeighted(rng, demoLocales),
+			Channel:       pickWeighted(rng, demoChannels),
+			Source:        pickWeighted(rng, demoSources),
+			GameVersion:   pickWeighted(rng, demoGames),
+			SessionID:     rng.Intn(1 << 30),
+			LastSeenAt:    lastSeen,
+			CreatedAt:     createdAt,
+		}
+		normalizeRecord(&record)
+		records = append(records, record)
+
+		for s, n := 0, 1+rng.Intn(8); s < n; s++ {
+			start := createdAt.Add(time.Duration(rng.Int63n(int64(lastSeen.Sub(createdAt)) + 1)))
+			end := start.Add(time.Duration(1+rng.Intn(90)) * time.Minute)
+			session := SessionRecord{
+				MachineID:  record.MachineID,
+				SessionID:  record.SessionID + s,
+				Version:    record.Version,
+				StartedAt:  start,
+				LastSeenAt: end,
+			}
+			if rng.Intn(4) > 0 {
+				session.EndedAt = &end
+			}
+			sessions = append(sessions, session)
+		}
+
+		if rng.Intn(5) == 0 {
+			logs = append(logs, ClientLog{
+				MachineID: record.MachineID,
+				Version:   record.Version,
+				Level:     []string{"warn", "error"}[rng.Intn(2)],
+				Message:   demoLogMessages[rng.Intn(len(demoLogMessages))],
+				LoggedAt:  lastSeen,
+				CreatedAt: lastSeen,
+			})
+		}
+		if rng.Intn(20) == 0 {
+			feedback = append(feedback, Feedback{
+				MachineID: record.MachineID,
+				Version:   record.Version,
+				Category:  []string{"bug", "suggestion", "other"}[rng.Intn(3)],
+				Message:   "演示反馈内容",
+				Status:    []string{"open", "resolved"}[rng.Intn(2)],
+				CreatedAt: lastSeen,
+			})
+		}
+		if rng.Intn(3) == 0 {
+			downloads = append(downloads, DownloadEvent{
+				LinkName:  "latest",
+				Version:   record.Version,
+				MachineID: record.MachineID,
+				IPHash:    fmt.Sprintf("%016x", rng.Int63()),
+				CreatedAt: createdAt,
+			})
+		}
+	}
+
+	// 重复执行时先清理上一次生成的演示数据
+	if err := clearDemoData(); err != nil {
+		return err
+	}
+	tx := db.Begin()
+	for _, batch := range []any{records, sessions, logs, feedback, downloads} {
+		if err := tx.CreateInBatches(batch, 200).Error; err != nil && !errors.Is(err, gorm.ErrEmptySlice) {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+	invalidateStatsCache()
+	log.Printf("已生成演示数据: %d 个用户, %d 个会话, %d 条日志, %d 条反馈, %d 次下载",
+		len(records), len(sessions), len(logs), len(feedback), len(downloads))
+	return nil
+}
+
+// clearDemoData 删除所有带演示前缀的数据
+func clearDemoData() error {
+	pattern := demoMachinePrefix + "%"
+	for _, model := range []any{&SessionRecord{}, &ClientLog{}, &Feedback{}, &DownloadEvent{}} {
+		if err := db.Where("machine_id LIKE ?", pattern).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+	err := db.Unscoped().Where("machine_id LIKE ?", pattern).Delete(&TelemetryRecord{}).Error
+	invalidateStatsCache()
+	return err
+}
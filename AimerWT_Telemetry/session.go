@@ -0,0 +1,95 @@
+package main
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	SessionEventStart = "start"
+	SessionEventEnd   = "end"
+)
+
+// touchSession 以 machine_id + session_id 标识一次会话, 首次出现时记录开始时间, 之后仅刷新最近活跃时间
+func touchSession(record TelemetryRecord) {
+	if record.SessionID == 0 {
+		return
+	}
+	now := time.Now()
+	db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "machine_id"}, {Name: "session_id"}},
+		DoUpdates: clause.Assignments(map[string]any{"last_seen_at": now}),
+	}).Create(&SessionRecord{
+		MachineID:  record.MachineID,
+		SessionID:  record.SessionID,
+		Version:    record.Version,
+		StartedAt:  now,
+		LastSeenAt: now,
+	})
+}
+
+func endSession(machineID string, sessionID int) error {
+	now := time.Now()
+	return db.Model(&SessionRecord{}).
+		Where("machine_id = ? AND session_id = ? AND ended_at IS NULL", machineID, sessionID).
+		Updates(map[string]any{"ended_at": now, "last_seen_at": now}).Error
+}
+
+type SessionStats struct {
+	Sessions            int64            `json:"sessions"`
+	Users               int64            `json:"users"`
+	AvgMinutes          float64          `json:"avg_minutes"`
+	SessionsPerUser     float64          `json:"sessions_per_user"`
+	TotalHours          float64          `json:"total_hours"`
+	DurationBuckets     []map[string]any `json:"duration_buckets"`
+	DailyUsage          []map[string]any `json:"daily_usage"`
+	AvgMinutesByVersion []map[string]any `json:"avg_minutes_by_version"`
+}
+
+// sessionDurationExpr 会话时长 (分钟), 未收到结束事件时以最后一次心跳为准
+const sessionDurationExpr = "(julianday(COALESCE(ended_at, last_seen_at)) - julianday(started_at)) * 1440"
+
+func sessionStats(days int) SessionStats {
+	var stats SessionStats
+	base := excludeBlockedMachines(db.Model(&SessionRecord{})).Where("started_at > ?", time.Now().AddDate(0, 0, -days))
+
+	var agg struct {
+		Sessions   int64
+		Users      int64
+		AvgMinutes float64
+		TotalMin   float64
+	}
+	base.Session(&gorm.Session{}).
+		Select("count(*) as sessions, count(distinct machine_id) as users, avg(" + sessionDurationExpr + ") as avg_minutes, sum(" + sessionDurationExpr + ") as total_min").
+		Scan(&agg)
+
+	stats.Sessions = agg.Sessions
+	stats.Users = agg.Users
+	stats.AvgMinutes = agg.AvgMinutes
+	stats.TotalHours = agg.TotalMin / 60
+	if agg.Users > 0 {
+		stats.SessionsPerUser = float64(agg.Sessions) / float64(agg.Users)
+	}
+
+	base.Session(&gorm.Session{}).
+		Select(`case
+			when ` + sessionDurationExpr + ` < 5 then '<5m'
+			when ` + sessionDurationExpr + ` < 15 then '5-15m'
+			when ` + sessionDurationExpr + ` < 30 then '15-30m'
+			when ` + sessionDurationExpr + ` < 60 then '30-60m'
+			when ` + sessionDurationExpr + ` < 120 then '1-2h'
+			else '>2h' end as name, count(*) as value, min(` + sessionDurationExpr + `) as lower`).
+		Group("name").Order("lower asc").Scan(&stats.DurationBuckets)
+
+	base.Session(&gorm.Session{}).
+		Select("date(started_at) as date, count(*) as sessions, sum(" + sessionDurationExpr + ") / 60 as hours").
+		Group("date").Order("date asc").Scan(&stats.DailyUsage)
+
+	base.Session(&gorm.Session{}).
+		Select("version as name, avg(" + sessionDurationExpr + ") as value").
+		Group("version").Order("value desc").Scan(&stats.AvgMinutesByVersion)
+
+	return stats
+}
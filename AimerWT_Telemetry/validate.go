@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var (
+	maxTelemetryBodyBytes = int64(envInt("TELEMETRY_MAX_BODY_BYTES", 16*1024))
+	maxClientBodyBytes    = int64(envInt("TELEMETRY_MAX_CLIENT_BODY_BYTES", 512*1024))
+	maxAuthorBodyBytes    = int64(envInt("TELEMETRY_MAX_AUTHOR_BODY_BYTES", 64*1024))
+)
+
+var (
+	machineIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+	versionPattern   = regexp.MustCompile(`^v?\d{1,4}(\.\d{1,4}){0,3}([-+][0-9A-Za-z.]{1,16})?$`)
+)
+
+const maxFieldLen = 128
+
+// limitBody 限制请求体大小, 声明了 Content-Length 的直接拒绝, 分块传输的在读取超限时由 bindError 返回 413
+func limitBody(c *gin.Context, limit int64) bool {
+	if c.Request.ContentLength > limit {
+		c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Payload Too Large"})
+		return false
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+	return true
+}
+
+// bindError 输出请求体解析失败的响应, err 为 nil 表示字段校验未通过
+func bindError(c *gin.Context, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Payload Too Large"})
+		return
+	}
+	c.JSON(400, gin.H{"error": "Invalid JSON"})
+}
+
+// validateRecord 校验客户端上报的记录, 拒绝超长、非 UTF-8 或格式异常的字段, 并清空只能由服务端维护的字段
+func validateRecord(record *TelemetryRecord) error {
+	if !machineIDPattern.MatchString(record.MachineID) {
+		return errors.New("invalid machine_id")
+	}
+	if !versionPattern.MatchString(record.Version) {
+		return errors.New("invalid version")
+	}
+	if record.CPUCount < 0 || record.CPUCount > 1024 {
+		return errors.New("invalid cpu_count")
+	}
+
+	fields := map[string]string{
+		"os":             record.OS,
+		"os_release":     record.OSRelease,
+		"os_version":     record.OSVersion,
+		"arch":           record.Arch,
+		"screen_res":     record.ScreenRes,
+		"python_version": record.PythonVersion,
+		"locale":         record.Locale,
+		"channel":        record.Channel,
+		"source":         record.Source,
+		"game_version":   record.GameVersion,
+	}
+	for name, value := range fields {
+		if len(value) > maxFieldLen {
+			return fmt.Errorf("%s too long", name)
+		}
+		// encoding/json 会把非法 UTF-8 字节替换为 U+FFFD, 因此同时拒绝替换字符
+		if !utf8.ValidString(value) || strings.ContainsRune(value, utf8.RuneError) {
+			return fmt.Errorf("%s is not valid UTF-8", name)
+		}
+	}
+
+	record.ID = 0
+	record.Alias = ""
+	record.PendingCommand = ""
+	record.CreatedAt = time.Time{}
+	record.DeletedAt = gorm.DeletedAt{}
+	return nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestValidateRecord(t *testing.T) {
+	valid := func() TelemetryRecord {
+		return TelemetryRecord{MachineID: "abc_DEF-123", Version: "3.1.0", OS: "Windows", CPUCount: 8, Locale: "zh_CN"}
+	}
+	tests := []struct {
+		name    string
+		modify  func(r *TelemetryRecord)
+		wantErr string
+	}{
+		{name: "valid", modify: func(r *TelemetryRecord) {}},
+		{name: "prefixed prerelease version", modify: func(r *TelemetryRecord) { r.Version = "v3.1.0-beta.2" }},
+		{name: "non-ascii field", modify: func(r *TelemetryRecord) { r.Locale = "中文" }},
+		{name: "empty machine_id", modify: func(r *TelemetryRecord) { r.MachineID = "" }, wantErr: "invalid machine_id"},
+		{name: "machine_id with space", modify: func(r *TelemetryRecord) { r.MachineID = "a b" }, wantErr: "invalid machine_id"},
+		{name: "machine_id too long", modify: func(r *TelemetryRecord) { r.MachineID = strings.Repeat("a", 65) }, wantErr: "invalid machine_id"},
+		{name: "empty version", modify: func(r *TelemetryRecord) { r.Version = "" }, wantErr: "invalid version"},
+		{name: "garbage version", modify: func(r *TelemetryRecord) { r.Version = "latest" }, wantErr: "invalid version"},
+		{name: "negative cpu", modify: func(r *TelemetryRecord) { r.CPUCount = -1 }, wantErr: "invalid cpu_count"},
+		{name: "huge cpu", modify: func(r *TelemetryRecord) { r.CPUCount = 1025 }, wantErr: "invalid cpu_count"},
+		{name: "field too long", modify: func(r *TelemetryRecord) { r.OS = strings.Repeat("x", maxFieldLen+1) }, wantErr: "os too long"},
+		{name: "field at limit", modify: func(r *TelemetryRecord) { r.Arch = strings.Repeat("x", maxFieldLen) }},
+		{name: "invalid utf-8", modify: func(r *TelemetryRecord) { r.Channel = "beta\xff" }, wantErr: "channel is not valid UTF-8"},
+		{name: "replacement char", modify: func(r *TelemetryRecord) { r.Source = "web\uFFFD" }, wantErr: "source is not valid UTF-8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := valid()
+			tt.modify(&r)
+			err := validateRecord(&r)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateRecordClearsServerFields(t *testing.T) {
+	r := TelemetryRecord{
+		MachineID: "m1", Version: "3.0", ID: 42, Alias: "boss", PendingCommand: `{"type":"popup"}`,
+		CreatedAt: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+		DeletedAt: gorm.DeletedAt{Time: time.Now(), Valid: true},
+	}
+	if err := validateRecord(&r); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.ID != 0 || r.Alias != "" || r.PendingCommand != "" || !r.CreatedAt.IsZero() || r.DeletedAt.Valid {
+		t.Errorf("server-maintained fields not cleared: %+v", r)
+	}
+}
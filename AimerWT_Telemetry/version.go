@@ -0,0 +1,172 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// compareVersions 按数字段比较版本号 (忽略前缀 v 与 -beta 等后缀), 返回 -1/0/1
+func compareVersions(a, b string) int {
+	pa, pb := versionParts(a), versionParts(b)
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func versionParts(v string) []int {
+	v = strings.TrimPrefix(strings.TrimSpace(strings.ToLower(v)), "v")
+	if i := strings.IndexAny(v, "-+ "); i >= 0 {
+		v = v[:i]
+	}
+	var parts []int
+	for _, s := range strings.Split(v, ".") {
+		n, _ := strconv.Atoi(s)
+		parts = append(parts, n)
+	}
+	return parts
+}
+
+type CompatPolicy struct {
+	Supported   bool   `json:"supported"`
+	MinVersion  string `json:"min_version"`
+	ForceUpdate bool   `json:"force_update"`
+	UpdateUrl   string `json:"update_url"`
+	Message     string `json:"message"`
+	Unsafe      bool   `json:"unsafe"` // 客户端需禁用安装等会修改游戏目录的操作
+}
+
+// buildCompatPolicy 根据最低支持版本与问题版本列表判断客户端是否需要强制更新
+func buildCompatPolicy(version string) CompatPolicy {
+	policy := CompatPolicy{
+		Supported:  true,
+		MinVersion: sysConfig.MinVersion,
+		UpdateUrl:  sysConfig.UpdateUrl,
+	}
+	if sysConfig.MinVersion != "" && compareVersions(version, sysConfig.MinVersion) < 0 {
+		policy.Supported = false
+		policy.ForceUpdate = sysConfig.ForceUpdate
+		policy.Message = sysConfig.MinVersionMsg
+	}
+	if strings.TrimSpace(sysConfig.UnsafeVersions) != "" && matchTarget(sysConfig.UnsafeVersions, version) {
+		policy.Supported = false
+		policy.ForceUpdate = true
+		policy.Unsafe = true
+		policy.Message = sysConfig.UnsafeVersionMsg
+	}
+	return policy
+}
+
+type VersionAdoption struct {
+	Version    string    `json:"version"`
+	FirstSeen  string    `json:"first_seen"`
+	Share      []float64 `json:"share"` // 每日活跃用户中使用该版本的比例 (%)
+	Users      []int64   `json:"users"`
+	DaysToHalf *int      `json:"days_to_half"` // 首次出现后占比达到 50% 所用天数, 未达到为 null
+}
+
+type VersionAdoptionResponse struct {
+	Dates    []string          `json:"dates"`
+	Versions []VersionAdoption `json:"versions"`
+}
+
+// versionAdoption 基于会话记录统计各版本每日活跃占比, 用于观察新版本的升级速度
+func versionAdoption(days int) VersionAdoptionResponse {
+	since := time.Now().AddDate(0, 0, -days)
+	resp := VersionAdoptionResponse{Dates: []string{}, Versions: []VersionAdoption{}}
+
+	var totals []struct {
+		Date  string
+		Users int64
+	}
+	excludeBlockedMachines(db.Model(&SessionRecord{})).
+		Select("date(started_at) as date, count(distinct machine_id) as users").
+		Where("started_at > ?", since).
+		Group("date").Order("date asc").
+		Scan(&totals)
+
+	var rows []struct {
+		Date    string
+		Version string
+		Users   int64
+	}
+	excludeBlockedMachines(db.Model(&SessionRecord{})).
+		Select("date(started_at) as date, version, count(distinct machine_id) as users").
+		Where("started_at > ?", since).
+		Group("date, version").
+		Scan(&rows)
+
+	var firstSeen []struct {
+		Version   string
+		FirstSeen string
+	}
+	excludeBlockedMachines(db.Model(&SessionRecord{})).
+		Select("version, date(min(started_at)) as first_seen").
+		Group("version").
+		Scan(&firstSeen)
+
+	dateIndex := make(map[string]int, len(totals))
+	for i, t := range totals {
+		dateIndex[t.Date] = i
+		resp.Dates = append(resp.Dates, t.Date)
+	}
+
+	byVersion := map[string]*VersionAdoption{}
+	for _, r := range rows {
+		v, ok := byVersion[r.Version]
+		if !ok {
+			v = &VersionAdoption{Version: r.Version, Share: make([]float64, len(totals)), Users: make([]int64, len(totals))}
+			byVersion[r.Version] = v
+		}
+		i := dateIndex[r.Date]
+		v.Users[i] = r.Users
+		if totals[i].Users > 0 {
+			v.Share[i] = math.Round(float64(r.Users)*10000/float64(totals[i].Users)) / 100
+		}
+	}
+
+	for _, f := range firstSeen {
+		v, ok := byVersion[f.Version]
+		if !ok {
+			continue
+		}
+		v.FirstSeen = f.FirstSeen
+		first, err := time.Parse("2006-01-02", f.FirstSeen)
+		if err != nil {
+			continue
+		}
+		for i, share := range v.Share {
+			if share < 50 {
+				continue
+			}
+			if d, err := time.Parse("2006-01-02", resp.Dates[i]); err == nil {
+				n := int(d.Sub(first).Hours() / 24)
+				v.DaysToHalf = &n
+			}
+			break
+		}
+	}
+
+	for _, v := range byVersion {
+		resp.Versions = append(resp.Versions, *v)
+	}
+	sort.Slice(resp.Versions, func(i, j int) bool {
+		return compareVersions(resp.Versions[i].Version, resp.Versions[j].Version) > 0
+	})
+	return resp
+}
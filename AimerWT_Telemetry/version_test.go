@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"v1.2", "1.2.0", 0},
+		{"V2.0", "v2.0", 0},
+		{"1.10", "1.9", 1},
+		{"1.9", "1.10", -1},
+		{"1.2", "1.2.1", -1},
+		{"1.2.1", "1.2", 1},
+		{"2.0-beta", "2.0", 0},
+		{"2.0+build.5", "2.0", 0},
+		{"3.0 (dev)", "3.0", 0},
+		{"", "0", 0},
+		{"", "0.0.1", -1},
+		{"10.0", "9.99.99", 1},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
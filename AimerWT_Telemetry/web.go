@@ -0,0 +1,153 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"html/template"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed web
+var embeddedWeb embed.FS
+
+// webDir 指向磁盘上的 web 目录时优先使用其中的文件, 便于不重新编译即可调整仪表盘
+var webDir = envString("TELEMETRY_WEB_DIR", "")
+
+const defaultLang = "zh-CN"
+
+// overlayFS 先查找磁盘覆盖目录, 找不到时回退到内嵌资源
+type overlayFS struct {
+	disk     fs.FS
+	embedded fs.FS
+}
+
+func (o overlayFS) Open(name string) (fs.File, error) {
+	if o.disk != nil {
+		if f, err := o.disk.Open(name); err == nil {
+			return f, nil
+		}
+	}
+	return o.embedded.Open(name)
+}
+
+func webAssets() fs.FS {
+	embedded, _ := fs.Sub(embeddedWeb, "web")
+	var disk fs.FS
+	if webDir != "" {
+		disk = os.DirFS(webDir)
+	}
+	return overlayFS{disk: disk, embedded: embedded}
+}
+
+func availableLangs() []string {
+	entries, err := fs.ReadDir(webAssets(), "i18n")
+	if err != nil {
+		return []string{defaultLang}
+	}
+	var langs []string
+	for _, e := range entries {
+		if name, ok := strings.CutSuffix(e.Name(), ".json"); ok {
+			langs = append(langs, name)
+		}
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+func loadMessages(lang string) map[string]string {
+	messages := map[string]string{}
+	for _, l := range []string{defaultLang, lang} {
+		data, err := fs.ReadFile(webAssets(), "i18n/"+l+".json")
+		if err != nil {
+			continue
+		}
+		if err := json.Unmarshal(data, &messages); err != nil {
+			log.Printf("解析语言文件 %s 失败: %v", l, err)
+		}
+	}
+	return messages
+}
+
+// resolveLang 依次参考 lang 参数、Cookie 与 Accept-Language 选择仪表盘语言
+func resolveLang(c *gin.Context) string {
+	langs := availableLangs()
+	supported := func(lang string) bool {
+		for _, l := range langs {
+			if l == lang {
+				return true
+			}
+		}
+		return false
+	}
+
+	if lang := c.Query("lang"); supported(lang) {
+		c.SetCookie("dashboard_lang", lang, 365*24*3600, "/", "", false, true)
+		return lang
+	}
+	if lang, err := c.Cookie("dashboard_lang"); err == nil && supported(lang) {
+		return lang
+	}
+	for _, part := range strings.Split(c.GetHeader("Accept-Language"), ",") {
+		tag := strings.TrimSpace(strings.SplitN(part, ";", 2)[0])
+		if supported(tag) {
+			return tag
+		}
+		for _, l := range langs {
+			if tag != "" && strings.HasPrefix(l, strings.SplitN(tag, "-", 2)[0]) {
+				return l
+			}
+		}
+	}
+	return defaultLang
+}
+
+func renderDashboard(c *gin.Context) {
+	lang := resolveLang(c)
+	messages := loadMessages(lang)
+
+	tmpl, err := template.New("dashboard.html").Funcs(template.FuncMap{
+		"t": func(key string) string {
+			if msg, ok := messages[key]; ok {
+				return msg
+			}
+			return key
+		},
+	}).ParseFS(webAssets(), "templates/dashboard.html")
+	if err != nil {
+		log.Printf("加载仪表盘模板失败: %v", err)
+		c.String(500, "dashboard template error")
+		return
+	}
+
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	err = tmpl.Execute(c.Writer, gin.H{
+		"Lang":           lang,
+		"Langs":          availableLangs(),
+		"Messages":       messages,
+		"RefreshSeconds": dashboardRefreshSeconds,
+		"OnlineMinutes":  onlineMinutes,
+	})
+	if err != nil {
+		log.Printf("渲染仪表盘失败: %v", err)
+	}
+}
+
+func serveDashboardAsset(c *gin.Context) {
+	name := "assets/" + strings.TrimPrefix(c.Param("filepath"), "/")
+	if !fs.ValidPath(name) {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	if _, err := fs.Stat(webAssets(), name); err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	c.FileFromFS(name, http.FS(webAssets()))
+}
@@ -18,13 +18,25 @@ except Exception as _e:
     _WEBVIEW_IMPORT_ERROR = _e
 
 from pathlib import Path
+from services.activity_manager import ActivityManager
 from services.config_manager import ConfigManager
-from services.core_logic import CoreService
-from services.library_manager import ArchivePasswordCanceled, LibraryManager
+from services.core_logic import CoreService, is_excluded
+from services.game_monitor import GameMonitor, GamePathMonitor, is_game_running
+from services.library_manager import ArchiveChecksumMismatch, ArchivePasswordCanceled, LibraryManager
 from utils.logger import setup_logger, get_logger, set_ui_callback
 from services.sights_manager import SightsManager
+from services.health_check import run_health_check
+from services.operation_report import list_reports, read_report, reports_dir, save_report
+from services.restore_points import create_restore_point, list_restore_points, rollback_restore_point
+from services.sandbox_install import run_sandbox_install
+from services.self_test import run_self_test
+from services.storage_advisor import analyze_storage, clean_storage, analyze_game_cache, clear_game_cache
 from services.skins_manager import SkinsManager
-from services.telemetry_manager import init_telemetry, get_hwid
+from services.telemetry_manager import (
+    init_telemetry, get_hwid, is_feature_enabled, ack_announcement,
+    get_experiment_variant, track_experiment_event, submit_feedback,
+    get_compat_policy, is_install_blocked
+)
 
 APP_VERSION = "2.1.0"
 AGREEMENT_VERSION = "2026-01-10"
@@ -193,6 +205,11 @@ class AppApi:
         self._skins_mgr = SkinsManager()
         self._sights_mgr = SightsManager()
         self._logic = CoreService()
+        self._activity = ActivityManager()
+        self._lib_mgr.import_callback = self._on_mod_imported
+        self._game_monitor = GameMonitor(on_change=self._on_game_status_change)
+        self._path_monitor = GamePathMonitor(self._cfg_mgr.get_game_path, on_change=self._on_game_path_status_change)
+        self._health_report = None
 
         # 初始化遥测系统
         if self._cfg_mgr.get_telemetry_enabled():
@@ -200,6 +217,7 @@ class AppApi:
             tm.set_server_message_callback(self.on_server_message)
             tm.set_user_command_callback(self.on_user_command)
             tm.set_log_callback(self._logger)
+            tm.set_game_version_provider(self._logic.get_game_version)
 
         self._search_running = False
         self._is_busy = False
@@ -212,6 +230,8 @@ class AppApi:
         self._last_alert_content = None  # 紧急通知 (弹窗)
         self._last_notice_content = None  # 公告栏 (左下角的)
         self._last_update_content = None  # 更新提示
+        self._last_unsafe_notice = None  # 问题版本强制更新提示
+        self._last_compat_notice = None  # 低于最低支持版本提示
         self._last_maintenance_status = None  # 维护模式
         self._last_announce_content = None  # 兼容以前的 key (可选)
 
@@ -247,6 +267,7 @@ class AppApi:
                     self._logger.info(f"[通知] {title}")
                     self._window.evaluate_js(safe_js_call("showAlert", title, content, "info"))
                     self._last_alert_content = full_alert_key
+                    ack_announcement(config.get("alert_id"))
 
             # 3. 公告栏常驻内容 (Notice - 发现有效内容则覆盖首页公告)
             if config.get("notice_active"):
@@ -254,6 +275,7 @@ class AppApi:
                 if notice_content and (self._last_notice_content != notice_content):
                     self._window.evaluate_js(safe_js_call("updateNoticeBar", notice_content))
                     self._last_notice_content = notice_content
+                    ack_announcement(config.get("notice_id"))
 
             # 4. 更新提示 (内容变化时才提示)
             if config.get("update_active"):
@@ -266,13 +288,39 @@ class AppApi:
                     self._window.evaluate_js(safe_js_call("showAlert", "发现新版本", content, "success", update_url))
                     self._last_update_content = update_key
 
+            # 5. 问题版本远程熔断 (禁用安装并强制提示更新)
+            compat = get_compat_policy()
+            if compat.get("unsafe"):
+                content = compat.get("message") or "当前版本存在严重问题，安装功能已暂停，请立即更新到最新版本。"
+                if self._last_unsafe_notice != content:
+                    self._logger.warning(f"[更新] 当前版本已被标记为问题版本: {content}")
+                    self._window.evaluate_js(
+                        safe_js_call("showAlert", "请立即更新", content, "error", compat.get("update_url", ""))
+                    )
+                    self._last_unsafe_notice = content
+
+            # 6. 低于最低支持版本 (强制更新时锁定界面并禁用安装，否则仅提示)
+            elif compat.get("supported") is False:
+                content = compat.get("message") or "当前版本已不再受支持，请更新到最新版本。"
+                force = bool(compat.get("force_update"))
+                compat_key = f"{force}|{content}"
+                if self._last_compat_notice != compat_key:
+                    update_url = compat.get("update_url", "")
+                    if force:
+                        self._logger.warning(f"[更新] 当前版本需要强制更新: {content}")
+                        self._window.evaluate_js(safe_js_call("showForceUpdate", "需要更新", content, update_url))
+                    else:
+                        self._logger.warning(f"[更新] 当前版本已不再受支持: {content}")
+                        self._window.evaluate_js(safe_js_call("showAlert", "建议更新", content, "warn", update_url))
+                    self._last_compat_notice = compat_key
+
         except Exception as e:
             print(f"消息处理异常: {e}")
 
     def on_user_command(self, cmd_json: str):
-        """处理针对当前用户的特定指令驱动"""
+        """处理针对当前用户的特定指令驱动，执行失败时抛出异常以便回执给服务端"""
         if not self._window:
-            return
+            raise RuntimeError("窗口尚未就绪")
 
         import json
         try:
@@ -291,13 +339,19 @@ class AppApi:
             elif cmd_type == "toast":
                 self._logger.info(f"[CMD] 收到管理员信息: {msg}")
                 self._window.evaluate_js(safe_js_call("showWarnToast", "管理员消息", msg, 5000))
+            else:
+                raise ValueError(f"未知指令类型: {cmd_type}")
 
         except Exception as e:
             print(f"专用指令解析异常: {e}")
+            raise
 
     def set_window(self, window):
         # 绑定 PyWebview Window 实例到桥接层，供后续 API 调用使用。
         self._window = window
+        self._game_monitor.start()
+        self._path_monitor.check()
+        self._path_monitor.start()
 
     def _load_json_with_fallback(self, file_path):
         # 按编码回退策略读取 JSON 文件并解析为 Python 对象。
@@ -410,6 +464,10 @@ class AppApi:
         except Exception:
             core_ready = False
 
+        # 退出前结束并保存进行中的游戏会话记录
+        self._game_monitor.stop()
+        self._path_monitor.stop()
+
         if not core_ready:
             os._exit(0)
 
@@ -428,6 +486,8 @@ class AppApi:
             is_valid, _ = self._logic.validate_game_path(path)
             if is_valid:
                 log.info(f"[INIT] 已加载配置路径: {path}")
+                self._warn_write_access()
+                self._sync_mod_usage()
             else:
                 log.warning(f"配置路径失效: {path}")
 
@@ -450,6 +510,45 @@ class AppApi:
             "telemetry_enabled": self._cfg_mgr.get_telemetry_enabled()
         }
 
+    def _on_game_status_change(self, status):
+        # 游戏启动/退出时通知前端刷新状态显示。
+        if not self._window:
+            return
+        status_js = json.dumps(status, ensure_ascii=False)
+        self._window.evaluate_js(f"if(window.app && app.onGameStatusChange) app.onGameStatusChange({status_js})")
+
+    def get_game_status(self):
+        # 返回游戏当前是否在运行、本次会话时长与近期游玩记录。
+        return {"success": True, "status": self._game_monitor.get_status()}
+
+    def _on_game_path_status_change(self, status):
+        # 游戏路径失效或恢复时通知前端，失效时提示重新搜索路径。
+        if not self._window:
+            return
+        status_js = json.dumps(status, ensure_ascii=False)
+        self._window.evaluate_js(f"if(window.app && app.onGamePathStatusChange) app.onGamePathStatusChange({status_js})")
+
+    def get_game_path_status(self):
+        # 立即复查游戏路径并返回结果。
+        return {"success": True, "status": self._path_monitor.check()}
+
+    def emit_health_report(self):
+        # 启动后执行自检，并将结构化报告推送给前端展示可操作的提示。
+        try:
+            self._health_report = run_health_check(self._cfg_mgr, self._lib_mgr, self._logic, WEB_DIR / "themes")
+        except Exception as e:
+            log.error(f"启动自检失败: {e}")
+            return
+        if self._window:
+            report_js = json.dumps(self._health_report, ensure_ascii=False)
+            self._window.evaluate_js(f"if(window.app && app.onHealthReport) app.onHealthReport({report_js})")
+
+    def get_health_report(self, refresh=False):
+        # 返回最近一次启动自检报告；refresh 为 True 或尚未自检时重新检查。
+        if refresh or self._health_report is None:
+            self._health_report = run_health_check(self._cfg_mgr, self._lib_mgr, self._logic, WEB_DIR / "themes")
+        return {"success": True, "report": self._health_report}
+
     def save_theme_selection(self, filename):
         # 保存前端选择的主题文件名到配置。
         self._cfg_mgr.set_active_theme(filename)
@@ -465,6 +564,41 @@ class AppApi:
         """
         return self._cfg_mgr.get_telemetry_enabled()
 
+    def is_feature_enabled(self, name):
+        """
+        功能定位:
+        - 查询服务端下发的远程功能开关（用于灰度发布高风险功能）。
+        """
+        return is_feature_enabled(name)
+
+    def get_experiment_variant(self, name):
+        """
+        功能定位:
+        - 查询当前机器在 A/B 实验中的分组，未参与时返回空字符串。
+        """
+        return get_experiment_variant(name)
+
+    def track_experiment_event(self, experiment, kind, goal=""):
+        """
+        功能定位:
+        - 上报实验曝光/转化事件 (kind: exposure | conversion)。
+        """
+        track_experiment_event(experiment, kind, goal)
+
+    def submit_feedback(self, category, message, include_diagnostics=False):
+        """
+        功能定位:
+        - 提交用户反馈 (category: bug | suggestion | question | other)，可选附带诊断包（系统信息与最近日志）。
+        """
+        if not message or not str(message).strip():
+            return {"success": False, "msg": "反馈内容不能为空"}
+        result = submit_feedback(category, str(message), bool(include_diagnostics))
+        if result.get("success"):
+            self._logger.info(f"[反馈] 已提交 ({category})")
+        else:
+            self._logger.warning(f"[反馈] {result.get('msg')}")
+        return result
+
     def set_telemetry_status(self, enabled):
         """
         功能定位:
@@ -480,6 +614,7 @@ class AppApi:
             tm.set_server_message_callback(self.on_server_message)
             tm.set_user_command_callback(self.on_user_command)
             tm.set_log_callback(self._logger)
+            tm.set_game_version_provider(self._logic.get_game_version)
 
             # 手动重启服务：先停止可能存在的旧循环，再启动新循环
             tm.stop()
@@ -490,6 +625,28 @@ class AppApi:
             tm.stop()
             self._logger.info("[SYS] 遥测服务已停用")
 
+    def check_game_write_access(self):
+        # 检测 sound/mod 与 UserSkins 是否可写，返回每个目录的结果与处理建议。
+        path = self._cfg_mgr.get_game_path()
+        valid, msg = self._logic.validate_game_path(path)
+        if not valid:
+            return {"success": False, "msg": msg or "未设置有效游戏路径"}
+        try:
+            results = self._logic.check_write_access()
+            return {"success": True, "ok": all(r["ok"] for r in results), "results": results}
+        except Exception as e:
+            log.error(f"写入权限检测失败: {e}")
+            return {"success": False, "msg": str(e)}
+
+    def _warn_write_access(self):
+        # 设置游戏路径后立即预检写入权限，在日志中提示需要处理的问题。
+        try:
+            for r in self._logic.check_write_access():
+                if not r["ok"]:
+                    log.warning(f"[CHECK] {r['target']} 无法写入: {r['hint']}")
+        except Exception as e:
+            log.debug(f"写入权限预检失败: {e}")
+
     def browse_folder(self):
         # 打开目录选择对话框，获取用户选择的游戏根目录并进行校验与保存。
         folder = self._window.create_file_dialog(webview.FileDialog.FOLDER)
@@ -499,6 +656,7 @@ class AppApi:
             if valid:
                 self._cfg_mgr.set_game_path(path)
                 log.info(f"[SUCCESS] 手动加载路径: {path}")
+                self._warn_write_access()
                 return {"valid": True, "path": path}
             else:
                 log.error(f"路径无效: {msg}")
@@ -571,6 +729,7 @@ class AppApi:
                 self._cfg_mgr.set_game_path(found_path)
                 self._logic.validate_game_path(found_path)
                 log.info("[SUCCESS] 自动搜索成功，路径已保存。")
+                self._warn_write_access()
 
                 # 通知前端更新 UI
                 path_js = json.dumps(found_path.replace(os.sep, "/"), ensure_ascii=False)
@@ -616,8 +775,9 @@ class AppApi:
                 except Exception as e:
                     log.error(f"图片转码失败: {e}")
 
-            # 补充 ID
+            # 补充 ID 与本地使用统计
             details["id"] = mod
+            details["usage"] = self._activity.get_usage(mod)
             result.append(details)
         if self._perf_enabled and t0 is not None:
             dt_ms = (time.perf_counter() - t0) * 1000.0
@@ -625,11 +785,15 @@ class AppApi:
         return result
 
     def open_folder(self, folder_type):
-        # 按类型打开资源相关目录（待解压区/语音包库/游戏目录/UserSkins）。
+        # 按类型打开资源相关目录（待解压区/语音包库/操作报告/游戏目录/UserSkins）。
         if folder_type == "pending":
             self._lib_mgr.open_pending_folder()
         elif folder_type == "library":
             self._lib_mgr.open_library_folder()
+        elif folder_type == "reports":
+            target_dir = reports_dir()
+            target_dir.mkdir(parents=True, exist_ok=True)
+            self._lib_mgr._open_folder_cross_platform(target_dir)
         elif folder_type == "game":
             path = self._cfg_mgr.get_game_path()
             if path and os.path.exists(path):
@@ -756,9 +920,13 @@ class AppApi:
                     hint = "密码错误，请重试" if reason == "incorrect" else ""
                     return self._request_archive_password(Path(archive_path).name, hint)
 
+                # 按导入规则分流：命中涂装/炮镜规则的压缩包导入对应位置，其余导入语音包库
+                routes = self._lib_mgr.route_pending(self._cfg_mgr.get_pending_rules())
+                self._import_routed_archives(routes)
                 self._lib_mgr.unzip_zips_to_library(
                     progress_callback=self.update_loading_ui,
                     password_provider=password_provider,
+                    archives=routes.get("voice", []),
                 )
 
                 # 完成后通知前端刷新列表
@@ -768,6 +936,8 @@ class AppApi:
                     self._window.evaluate_js(
                         f"if(window.MinimalistLoading) MinimalistLoading.update(100, {msg_js})"
                     )
+            except ArchiveChecksumMismatch as e:
+                self._on_checksum_mismatch(e)
             except ArchivePasswordCanceled:
                 log.warning("已取消输入密码，导入已终止")
                 if self._window:
@@ -788,6 +958,79 @@ class AppApi:
         t.daemon = True  # 设置为守护线程
         t.start()
 
+    def _import_routed_archives(self, routes):
+        # 将按导入规则分到涂装/炮镜的压缩包导入游戏 UserSkins / UserSights，单个失败不影响其余文件。
+        for archive in routes.get("skip", []):
+            log.info(f"[SKIPPED] 按导入规则跳过: {archive.name}")
+
+        skins = routes.get("skins", [])
+        if skins:
+            game_path = self._cfg_mgr.get_game_path()
+            valid, _ = self._logic.validate_game_path(game_path)
+            for archive in skins:
+                if not valid:
+                    log.warning(f"未设置有效游戏路径，跳过涂装: {archive.name}")
+                    continue
+                try:
+                    self._skins_mgr.import_skin_zip(archive, game_path)
+                    log.info(f"[SUCCESS] 按导入规则导入涂装: {archive.name}")
+                except FileExistsError:
+                    log.warning(f"[SKIPPED] 涂装已存在: {archive.name}")
+                except Exception as e:
+                    log.error(f"涂装导入失败 {archive.name}: {e}")
+            if self._window:
+                self._window.evaluate_js("if(app.refreshSkins) app.refreshSkins()")
+
+        sights = routes.get("sights", [])
+        if sights:
+            has_sights_path = bool(self._sights_mgr.get_usersights_path())
+            for archive in sights:
+                if not has_sights_path:
+                    log.warning(f"请先设置有效的 UserSights 路径，跳过炮镜: {archive.name}")
+                    continue
+                try:
+                    self._sights_mgr.import_sights_zip(archive)
+                    log.info(f"[SUCCESS] 按导入规则导入炮镜: {archive.name}")
+                except FileExistsError:
+                    log.warning(f"[SKIPPED] 炮镜已存在: {archive.name}")
+                except Exception as e:
+                    log.error(f"炮镜导入失败 {archive.name}: {e}")
+            if self._window:
+                self._window.evaluate_js("if(app.refreshSights) app.refreshSights()")
+
+    def get_pending_rules(self):
+        # 返回待解压区导入规则与可选目标，供设置页编辑。
+        return {
+            "success": True,
+            "rules": self._cfg_mgr.get_pending_rules(),
+            "targets": list(self._cfg_mgr.PENDING_RULE_TARGETS),
+        }
+
+    def save_pending_rules(self, rules):
+        # 保存待解压区导入规则，如 [{"pattern": "*skin*", "target": "skins"}]，按顺序匹配。
+        if isinstance(rules, str):
+            try:
+                rules = json.loads(rules)
+            except json.JSONDecodeError:
+                return {"success": False, "msg": "规则格式错误"}
+        if not isinstance(rules, list) or not all(isinstance(r, dict) for r in rules):
+            return {"success": False, "msg": "规则格式错误"}
+        try:
+            if not self._cfg_mgr.set_pending_rules(rules):
+                return {"success": False, "msg": "保存失败"}
+        except ValueError as e:
+            return {"success": False, "msg": str(e)}
+        return {"success": True, "rules": self._cfg_mgr.get_pending_rules()}
+
+    def _on_checksum_mismatch(self, e):
+        # 校验文件不一致时弹窗提示，批量导入中其他压缩包可能已成功导入，因此仍刷新语音包库。
+        log.error(f"导入校验失败: {e}")
+        if self._window:
+            self._window.evaluate_js("app.refreshLibrary()")
+            self._window.evaluate_js("if(window.MinimalistLoading) MinimalistLoading.hide()")
+            args = ", ".join(json.dumps(a, ensure_ascii=False) for a in ("校验失败", str(e), "error"))
+            self._window.evaluate_js(f"if(window.app && app.showAlert) app.showAlert({args})")
+
     def import_selected_zip(self):
         # 打开文件选择对话框导入单个 ZIP/RAR 到语音包库，并将进度同步到前端加载组件。
         if self._is_busy:
@@ -843,6 +1086,8 @@ class AppApi:
                         self._window.evaluate_js(
                             f"if(window.MinimalistLoading) MinimalistLoading.update(100, {msg_js})"
                         )
+                except ArchiveChecksumMismatch as e:
+                    self._on_checksum_mismatch(e)
                 except ArchivePasswordCanceled:
                     log.warning("已取消输入密码，导入已终止")
                     if self._window:
@@ -865,6 +1110,47 @@ class AppApi:
         else:
             pass
 
+    def _on_mod_imported(self, mod_name, archive_path):
+        # 记录导入来源与压缩包摘要，供导入历史与重新导入使用。
+        digest = ""
+        try:
+            digest = CoreService._file_digest(archive_path)
+        except OSError as e:
+            log.warning(f"计算压缩包摘要失败: {e}")
+        self._activity.record_import(mod_name, archive_path, digest)
+
+    def get_import_history(self):
+        # 返回导入过的压缩包记录（最新在前），并标注原始文件是否仍在、语音包是否仍在库中。
+        try:
+            history = self._activity.get_imports()
+            library_dir = self._lib_mgr.library_dir
+            for entry in history:
+                entry["source_exists"] = Path(entry["source"]).is_file()
+                entry["in_library"] = (library_dir / entry["mod"]).is_dir()
+            return {"success": True, "history": history}
+        except Exception as e:
+            log.warning(f"读取导入历史失败: {e}")
+            return {"success": False, "msg": str(e)}
+
+    def reimport_from_history(self, import_id):
+        # 从导入历史中的原始路径重新导入压缩包，用于误删语音包或重置语音包库之后。
+        entry = self._activity.get_import(import_id)
+        if not entry:
+            return {"success": False, "msg": "导入记录不存在"}
+        source = Path(entry["source"])
+        if not source.is_file():
+            return {"success": False, "msg": f"原始压缩包已不存在: {source}"}
+        if (self._lib_mgr.library_dir / entry["mod"]).is_dir():
+            return {"success": False, "msg": f"语音包库中已存在 {entry['mod']}，如需重新导入请先删除"}
+        try:
+            if entry.get("sha256") and CoreService._file_digest(source) != entry["sha256"]:
+                log.warning(f"原始压缩包自上次导入后内容已变化: {source.name}")
+        except OSError as e:
+            return {"success": False, "msg": f"读取原始压缩包失败: {e}"}
+        if not self.import_voice_zip_from_path(source):
+            return {"success": False, "msg": "另一个任务正在进行中，请稍候..."}
+        return {"success": True}
+
     def import_voice_zip_from_path(self, zip_path):
         """导入指定路径的压缩包"""
         if self._is_busy:
@@ -900,6 +1186,8 @@ class AppApi:
                     self._window.evaluate_js(
                         f"if(window.MinimalistLoading) MinimalistLoading.update(100, {msg_js})"
                     )
+            except ArchiveChecksumMismatch as e:
+                self._on_checksum_mismatch(e)
             except ArchivePasswordCanceled:
                 log.warning("已取消输入密码，导入已终止")
                 if self._window:
@@ -1103,6 +1391,16 @@ class AppApi:
                 log.error(f"解析安装列表失败: {install_list}")
                 return False
 
+        if is_install_blocked():
+            log.error("当前版本需要更新，安装功能已暂停，请先更新软件")
+            return False
+
+        # 游戏运行时 sound/mod 中的文件被占用，且复制一半会导致本局语音异常
+        self._game_monitor.update(is_game_running())
+        if self._game_monitor.running:
+            log.error("游戏正在运行，请退出游戏后再安装")
+            return False
+
         # 使用线程锁与状态位限制并发任务
         with self._lock:
             if self._is_busy:
@@ -1118,15 +1416,29 @@ class AppApi:
                 self._is_busy = False
             return False
 
+        # 安装前预检 sound/mod 写入权限，避免复制到一半才失败
+        denied = [r for r in self._logic.check_write_access(("sound/mod",)) if not r["ok"]]
+        if denied:
+            log.error(f"安装失败：{denied[0]['hint']}")
+            with self._lock:
+                self._is_busy = False
+            return False
+
         # 记录当前语音包标识，供前端在列表中标记已生效项
         self._cfg_mgr.set_current_mod(mod_name)
 
         def _run():
             try:
+                self._create_restore_point(f"安装 {mod_name}")
                 mod_path = self._lib_mgr.library_dir / mod_name
-                self._logic.install_from_library(
-                    mod_path, install_list, progress_callback=self.update_loading_ui
+                ok = self._logic.install_from_library(
+                    mod_path, install_list, progress_callback=self.update_loading_ui,
+                    exclude_patterns=self._get_install_excludes(mod_name)
                 )
+                if ok:
+                    self._activity.record("install", mod_name, files=list(install_list))
+                self._sync_mod_usage()
+                self._save_operation_report()
 
                 # 安装完成，通知前端
                 if self._window:
@@ -1153,6 +1465,99 @@ class AppApi:
         t.start()
         return True
 
+    def _get_install_excludes(self, mod_name):
+        # 合并语音包 info.json 中声明的 exclude 与用户为该语音包设置的排除规则。
+        patterns = []
+        try:
+            patterns.extend(self._lib_mgr.get_mod_details(mod_name).get("exclude") or [])
+        except Exception as e:
+            log.warning(f"读取语音包排除规则失败: {e}")
+        patterns.extend(self._cfg_mgr.get_install_excludes(mod_name))
+        return [str(p) for p in patterns if p]
+
+    def get_install_excludes(self, mod_name):
+        # 返回语音包自带与用户设置的安装排除规则，供详情页编辑。
+        try:
+            details = self._lib_mgr.get_mod_details(mod_name)
+            return {
+                "success": True,
+                "mod": list(details.get("exclude") or []),
+                "user": self._cfg_mgr.get_install_excludes(mod_name),
+            }
+        except Exception as e:
+            return {"success": False, "msg": str(e)}
+
+    def set_install_excludes(self, mod_name, patterns):
+        # 保存用户为语音包设置的安装排除规则，如 ["*_music_*.bank"]；传入空列表表示清除。
+        if isinstance(patterns, str):
+            try:
+                patterns = json.loads(patterns)
+            except json.JSONDecodeError:
+                patterns = [p for p in patterns.splitlines()]
+        if not isinstance(patterns, list):
+            return {"success": False, "msg": "排除规则格式错误"}
+        if not self._cfg_mgr.set_install_excludes(mod_name, patterns):
+            return {"success": False, "msg": "保存失败"}
+        return {"success": True, "patterns": self._cfg_mgr.get_install_excludes(mod_name)}
+
+    def mark_mod_viewed(self, mod_name):
+        # 前端打开语音包详情时调用，记录为最近查看，供首页「继续上次查看」使用。
+        if not mod_name:
+            return False
+        self._activity.record("view", mod_name)
+        return True
+
+    def get_recent_activity(self):
+        # 返回最近安装/查看的语音包，供首页提供「重新安装上次组合」「继续上次查看」快捷操作。
+        try:
+            recent = self._activity.get_recent()
+            library_dir = self._lib_mgr.library_dir
+            for key in ("last_install", "continue"):
+                item = recent.get(key)
+                if item:
+                    recent[key] = dict(item, exists=(library_dir / item["mod"]).is_dir())
+            for item in recent["mods"]:
+                item["exists"] = (library_dir / item["mod"]).is_dir()
+            return {"success": True, "recent": recent}
+        except Exception as e:
+            log.warning(f"读取最近活动失败: {e}")
+            return {"success": False, "msg": str(e)}
+
+    def _sync_mod_usage(self):
+        # 以安装清单中仍拥有文件的语音包为准，更新各语音包的生效时长统计。
+        if not self._logic.manifest_mgr:
+            return
+        try:
+            self._activity.sync_active(self._logic.manifest_mgr.manifest.get("file_map", {}).values())
+        except Exception as e:
+            log.warning(f"更新语音包使用统计失败: {e}")
+
+    def get_mod_history(self, mod_name):
+        # 返回语音包的私人备注与历史记录（导入、重新导入、安装次数等），供详情页展示。
+        try:
+            return {"success": True, "history": self._activity.get_history(mod_name)}
+        except Exception as e:
+            log.warning(f"读取语音包历史失败: {e}")
+            return {"success": False, "msg": str(e)}
+
+    def set_mod_note(self, mod_name, text):
+        # 保存语音包私人备注；备注存放在本地使用记录中，不写入语音包目录。
+        if not mod_name:
+            return {"success": False, "msg": "未指定语音包"}
+        if not self._activity.set_note(mod_name, text):
+            return {"success": False, "msg": "保存失败"}
+        return {"success": True}
+
+    def get_mod_usage_stats(self):
+        # 返回语音包库中每个语音包的安装次数与生效时长，供统计页找出从未使用的语音包。
+        try:
+            stats = [dict(self._activity.get_usage(mod), mod=mod) for mod in self._lib_mgr.scan_library()]
+            stats.sort(key=lambda item: (item["install_count"], item["active_seconds"]))
+            return {"success": True, "stats": stats}
+        except Exception as e:
+            log.warning(f"读取语音包使用统计失败: {e}")
+            return {"success": False, "msg": str(e)}
+
     def check_install_conflicts(self, mod_name, install_list):
         # 基于安装清单对本次安装可能写入的文件名进行冲突检查，并返回冲突明细列表。
         try:
@@ -1173,9 +1578,12 @@ class AppApi:
             if not mod_path.exists():
                 return []
 
-            # install_list 现在是文件路径列表，直接提取文件名
+            # install_list 现在是文件路径列表，直接提取文件名；被排除规则跳过的文件不参与冲突检测
+            excludes = self._get_install_excludes(mod_name)
             files_to_install = []
             for file_rel_path in install_list:
+                if is_excluded(file_rel_path, excludes):
+                    continue
                 # 只提取文件名
                 file_name = Path(file_rel_path).name
                 files_to_install.append(file_name)
@@ -1188,6 +1596,132 @@ class AppApi:
             log.warning(f"冲突检测失败: {e}")
             return []
 
+    def get_conflict_graph(self):
+        # 汇总语音包库中各语音包之间共用的 .bank 文件名，并标注游戏目录中当前的所属语音包，供前端绘制冲突矩阵。
+        try:
+            file_map = {}
+            if self._logic.manifest_mgr:
+                file_map = self._logic.manifest_mgr.manifest.get("file_map", {})
+
+            mods = sorted(self._lib_mgr.scan_library())
+            owners = {}
+            installed_conflicts = {}
+            for mod in mods:
+                install_list = self._lib_mgr.get_bank_files(mod)
+                for file_name in {Path(p).name for p in install_list}:
+                    owners.setdefault(file_name, []).append(mod)
+                # 与已安装语音包的冲突直接复用安装前的冲突检查
+                installed_conflicts[mod] = len(self.check_install_conflicts(mod, install_list))
+
+            files = []
+            edges = {}
+            for file_name, file_mods in sorted(owners.items()):
+                installed_by = file_map.get(file_name, "")
+                if len(file_mods) < 2 and (not installed_by or installed_by in file_mods):
+                    continue
+                files.append({"file": file_name, "mods": file_mods, "installed_by": installed_by})
+                for i, a in enumerate(file_mods):
+                    for b in file_mods[i + 1:]:
+                        edges.setdefault((a, b), []).append(file_name)
+
+            return {
+                "success": True,
+                "mods": [{"name": m, "installed_conflicts": installed_conflicts.get(m, 0)} for m in mods],
+                "files": files,
+                "edges": [{"source": a, "target": b, "files": f, "count": len(f)} for (a, b), f in edges.items()],
+            }
+        except Exception as e:
+            log.warning(f"生成冲突关系失败: {e}")
+            return {"success": False, "msg": str(e)}
+
+    def get_coverage_report(self, mod_name):
+        # 按 .bank 文件名汇总语音包覆盖的陆/空/海等内容类别与国籍，比 capabilities 标记更细。
+        try:
+            report = self._lib_mgr.get_coverage_report(mod_name)
+            return {"success": True, "report": report}
+        except Exception as e:
+            log.warning(f"生成覆盖范围报告失败: {e}")
+            return {"success": False, "msg": str(e)}
+
+    def compare_mods(self, mod_a, mod_b):
+        # 对比两个语音包覆盖的语音类型、语言与同名 .bank 文件，帮助用户在相似语音包间取舍或预估合并结果。
+        try:
+            return {"success": True, "diff": self._lib_mgr.compare_mods(mod_a, mod_b)}
+        except Exception as e:
+            log.warning(f"对比语音包失败: {e}")
+            return {"success": False, "msg": str(e)}
+
+    def run_sandbox_install(self, mod_name, install_list=None, keep_sandbox=False):
+        # 供语音包作者使用：将语音包安装到临时沙盒目录并执行全部校验，不触碰真实游戏目录。
+        if isinstance(install_list, str):
+            try:
+                install_list = json.loads(install_list)
+            except json.JSONDecodeError:
+                return {"success": False, "msg": "安装列表格式错误"}
+        try:
+            report = run_sandbox_install(self._lib_mgr, mod_name, install_list, bool(keep_sandbox))
+            return {"success": True, "report": report}
+        except Exception as e:
+            log.error(f"沙盒安装失败: {e}")
+            return {"success": False, "msg": str(e)}
+
+    def run_self_test(self):
+        # 在临时目录中完整执行导入、识别、安装、校验与还原流程，用于排查本机环境问题。
+        if self._is_busy:
+            return {"success": False, "msg": "另一个任务正在进行中，请稍候..."}
+        try:
+            return {"success": True, "report": run_self_test()}
+        except Exception as e:
+            log.error(f"自检失败: {e}")
+            return {"success": False, "msg": str(e)}
+
+    def analyze_storage(self):
+        # 统计待解压区、隔离区、沙盒临时目录与 sound/mod 中无安装记录文件的占用，供前端展示清理建议。
+        try:
+            # 游戏路径无效时仅跳过 sound/mod 相关统计
+            self._logic.validate_game_path(self._cfg_mgr.get_game_path())
+            return {"success": True, "report": analyze_storage(self._lib_mgr, self._logic)}
+        except Exception as e:
+            log.error(f"存储分析失败: {e}")
+            return {"success": False, "msg": str(e)}
+
+    def clean_storage(self, category, confirmed=False):
+        # 按类别一键清理 analyze_storage 统计出的文件；无安装记录的游戏文件需前端确认后传入 confirmed=True。
+        if self._is_busy:
+            return {"success": False, "msg": "另一个任务正在进行中，请稍候..."}
+        try:
+            self._logic.validate_game_path(self._cfg_mgr.get_game_path())
+            return {"success": True, "result": clean_storage(self._lib_mgr, self._logic, category, bool(confirmed))}
+        except Exception as e:
+            log.error(f"存储清理失败: {e}")
+            return {"success": False, "msg": str(e)}
+
+    def get_game_cache_info(self):
+        # 统计游戏缓存目录占用，供前端在清理前展示并请求确认。
+        path = self._cfg_mgr.get_game_path()
+        valid, msg = self._logic.validate_game_path(path)
+        if not valid:
+            return {"success": False, "msg": msg or "未设置有效游戏路径"}
+        try:
+            return {"success": True, "report": analyze_game_cache(self._logic)}
+        except Exception as e:
+            log.error(f"统计游戏缓存失败: {e}")
+            return {"success": False, "msg": str(e)}
+
+    def clear_game_cache(self):
+        # 清空游戏缓存目录，游戏下次启动时会自动重建。
+        if self._is_busy:
+            return {"success": False, "msg": "另一个任务正在进行中，请稍候..."}
+        path = self._cfg_mgr.get_game_path()
+        valid, msg = self._logic.validate_game_path(path)
+        if not valid:
+            return {"success": False, "msg": msg or "未设置有效游戏路径"}
+        try:
+            return {"success": True, "result": clear_game_cache(self._logic)}
+        except Exception as e:
+            log.error(f"清理游戏缓存失败: {e}")
+            return {"success": False, "msg": str(e)}
+
     def delete_mod(self, mod_name):
         # 从语音包库目录中删除指定语音包文件夹。
         if self._is_busy:
@@ -1215,10 +1749,15 @@ class AppApi:
         try:
             if not mod_name:
                 return {"success": False, "msg": "语音包名称为空"}
+            if is_install_blocked():
+                return {"success": False, "msg": "当前版本需要更新，安装功能已暂停，请先更新软件"}
             path = self._cfg_mgr.get_game_path()
             valid, msg = self._logic.validate_game_path(path)
             if not valid:
                 return {"success": False, "msg": msg or "未设置有效游戏路径"}
+            denied = [r for r in self._logic.check_write_access(("sound/mod",)) if not r["ok"]]
+            if denied:
+                return {"success": False, "msg": denied[0]["hint"]}
             result = self._lib_mgr.copy_country_files(
                 mod_name,
                 path,
@@ -1229,6 +1768,9 @@ class AppApi:
             created = result.get("created", [])
             skipped = result.get("skipped", [])
             missing = result.get("missing", [])
+            # 复制生成的文件记入该语音包的安装清单，卸载时一并删除
+            if created and self._logic.manifest_mgr:
+                self._logic.manifest_mgr.add_mod_files(mod_name, created)
             msg = f"复制完成，新增 {len(created)}"
             if skipped:
                 msg += f"，跳过 {len(skipped)}"
@@ -1261,7 +1803,10 @@ class AppApi:
 
         def _run():
             try:
+                self._create_restore_point("还原纯净模式")
                 self._logic.restore_game()
+                self._sync_mod_usage()
+                self._save_operation_report()
 
                 # 还原成功，清除状态
                 self._cfg_mgr.set_current_mod("")
@@ -1275,6 +1820,111 @@ class AppApi:
         t.start()
         return True
 
+    def _create_restore_point(self, reason):
+        # 在改写 sound/mod 前记录还原点；记录失败只提示，不阻止后续操作。
+        try:
+            create_restore_point(self._logic, reason)
+        except Exception as e:
+            log.warning(f"创建还原点失败: {e}")
+
+    def list_restore_points(self):
+        # 返回已保存的还原点列表（最新在前），供前端选择回滚目标。
+        try:
+            return {"success": True, "points": list_restore_points()}
+        except Exception as e:
+            log.warning(f"读取还原点失败: {e}")
+            return {"success": False, "msg": str(e)}
+
+    def rollback_restore_point(self, point_id):
+        # 将 sound/mod 与安装清单回滚到指定还原点，文件从语音包库中找回。
+        with self._lock:
+            if self._is_busy:
+                return {"success": False, "msg": "另一个任务正在进行中，请稍候..."}
+            self._is_busy = True
+        try:
+            valid, msg = self._logic.validate_game_path(self._cfg_mgr.get_game_path())
+            if not valid:
+                return {"success": False, "msg": msg or "未设置有效游戏路径"}
+            result = rollback_restore_point(self._logic, self._lib_mgr, point_id)
+            self._sync_mod_usage()
+            return {"success": True, "result": result}
+        except Exception as e:
+            log.error(f"回滚失败: {e}")
+            return {"success": False, "msg": str(e)}
+        finally:
+            with self._lock:
+                self._is_busy = False
+
+    def _save_operation_report(self):
+        # 将最近一次安装/还原的过程记录保存为文本报告，并通知前端可查看。
+        report = self._logic.last_report
+        if not report:
+            return
+        try:
+            path = save_report(report, APP_VERSION)
+        except Exception as e:
+            log.warning(f"保存操作报告失败: {e}")
+            return
+        if self._window:
+            name_js = json.dumps(path.name, ensure_ascii=False)
+            self._window.evaluate_js(f"if(window.app && app.onOperationReport) app.onOperationReport({name_js})")
+
+    def list_operation_reports(self):
+        # 返回已保存的安装/还原报告列表（最新在前）。
+        try:
+            return {"success": True, "reports": list_reports()}
+        except Exception as e:
+            return {"success": False, "msg": str(e)}
+
+    def get_operation_report(self, name=None):
+        # 读取指定报告的文本；未指定时返回最新一份，供用户反馈问题时附上。
+        try:
+            if not name:
+                reports = list_reports()
+                if not reports:
+                    return {"success": False, "msg": "暂无操作报告"}
+                name = reports[0]["name"]
+            return {"success": True, "name": name, "content": read_report(name)}
+        except Exception as e:
+            return {"success": False, "msg": str(e)}
+
+    def uninstall_mod(self, mod_name):
+        # 按安装清单卸载单个语音包：只删除该语音包写入 sound/mod 的文件，其余语音包不受影响。
+        # 使用线程锁与状态位限制并发任务
+        with self._lock:
+            if self._is_busy:
+                log.warning("另一个任务正在进行中，请稍候...")
+                return False
+            self._is_busy = True
+
+        path = self._cfg_mgr.get_game_path()
+        valid, msg = self._logic.validate_game_path(path)
+        if not valid:
+            log.error(f"卸载失败: {msg}")
+            with self._lock:
+                self._is_busy = False
+            return False
+
+        def _run():
+            try:
+                if not self._logic.uninstall_mod(mod_name):
+                    return
+                self._activity.record("uninstall", mod_name)
+                self._sync_mod_usage()
+                if self._cfg_mgr.get_current_mod() == mod_name:
+                    self._cfg_mgr.set_current_mod("")
+                if self._window:
+                    name_js = json.dumps(mod_name, ensure_ascii=False)
+                    self._window.evaluate_js(f"if(window.app && app.onUninstallSuccess) app.onUninstallSuccess({name_js})")
+            finally:
+                with self._lock:
+                    self._is_busy = False
+
+        t = threading.Thread(target=_run)
+        t.daemon = True
+        t.start()
+        return True
+
     def clear_logs(self):
         # 接收前端“清空日志”动作，并输出一条日志用于记录该行为。
         log.info("日志已清空")
@@ -1855,6 +2505,8 @@ def main() -> int:
         except Exception:
             log.exception("on_app_started 失败")
 
+        api.emit_health_report()
+
     # 启动
     icon_path = str(WEB_DIR / "assets" / "logo.ico")
     try:
@@ -0,0 +1,262 @@
+# -*- coding: utf-8 -*-
+"""
+使用记录模组：在本地记录语音包的安装与查看操作，供首页提供快捷操作。
+
+功能包括：
+- 记录最近安装（含安装的文件列表）与最近查看的语音包
+- 提供「重新安装上次的组合」「继续上次查看的语音包」所需的数据
+- 统计每个语音包的安装次数与生效时长（仍有文件留在 sound/mod 中即视为生效）
+- 保存用户为语音包写的私人备注（不写入语音包目录，导出语音包时不会带上），并汇总每个语音包的历史记录
+- 记录导入过的压缩包（来源路径、时间、语音包名、SHA-256），供重置语音包库或误删后重新导入
+
+数据存储于应用数据目录的 activity.json，仅保存在本机。
+"""
+import json
+import threading
+import uuid
+from datetime import datetime
+from pathlib import Path
+from typing import Any
+
+from utils.logger import get_logger
+from utils.utils import get_docs_data_dir
+
+log = get_logger(__name__)
+
+# 保留的操作记录条数上限
+MAX_EVENTS = 500
+
+# 保留的导入记录条数上限
+MAX_IMPORTS = 200
+
+# 备注长度上限
+MAX_NOTE_LENGTH = 2000
+
+# 计入语音包历史的操作类型（查看记录只用于首页快捷操作）
+HISTORY_ACTIONS = ("import", "install", "uninstall")
+
+
+class ActivityManager:
+    """
+    维护本地使用记录文件，提供记录写入与最近活动查询。
+
+    属性:
+        data_file: 记录文件路径
+        data: 记录数据字典
+    """
+
+    def __init__(self, data_file: Path | str | None = None):
+        self.data_file = Path(data_file) if data_file else get_docs_data_dir() / "activity.json"
+        self._lock = threading.Lock()
+        self.data = self._load()
+
+    def _load(self) -> dict[str, Any]:
+        data = {}
+        if self.data_file.exists():
+            try:
+                with open(self.data_file, "r", encoding="utf-8") as f:
+                    data = json.load(f)
+            except Exception as e:
+                log.warning(f"读取使用记录失败: {type(e).__name__}: {e}")
+        if not isinstance(data, dict):
+            data = {}
+        if not isinstance(data.get("events"), list):
+            data["events"] = []
+        if not isinstance(data.get("stats"), dict):
+            data["stats"] = {}
+        if not isinstance(data.get("notes"), dict):
+            data["notes"] = {}
+        if not isinstance(data.get("imports"), list):
+            data["imports"] = []
+        return data
+
+    def _save(self) -> bool:
+        try:
+            self.data_file.parent.mkdir(parents=True, exist_ok=True)
+            temp_file = self.data_file.with_suffix(".tmp")
+            with open(temp_file, "w", encoding="utf-8") as f:
+                json.dump(self.data, f, indent=2, ensure_ascii=False)
+            temp_file.replace(self.data_file)
+            return True
+        except Exception as e:
+            log.warning(f"保存使用记录失败: {type(e).__name__}: {e}")
+            return False
+
+    def record(self, action: str, mod_name: str, **extra: Any) -> None:
+        """
+        追加一条操作记录并落盘。
+
+        Args:
+            action: 操作类型，如 "import"、"install"、"uninstall"、"view"
+            mod_name: 语音包名称
+            extra: 附加字段，如安装时的 files
+        """
+        event = {"time": datetime.now().isoformat(timespec="seconds"), "action": action, "mod": mod_name}
+        event.update(extra)
+        with self._lock:
+            events = self.data["events"]
+            events.append(event)
+            del events[:-MAX_EVENTS]
+            if action == "install":
+                stats = self._stats_entry(mod_name)
+                stats["install_count"] += 1
+                stats["last_installed"] = event["time"]
+            self._save()
+
+    def record_import(self, mod_name: str, source: Path | str, sha256: str) -> None:
+        """
+        记录一次压缩包导入，并同时写入语音包历史。
+
+        Args:
+            mod_name: 导入后的语音包名称
+            source: 压缩包原始路径
+            sha256: 压缩包摘要，重新导入时用于判断来源文件是否已变化
+        """
+        entry = {
+            "id": uuid.uuid4().hex[:12],
+            "time": datetime.now().isoformat(timespec="seconds"),
+            "mod": mod_name,
+            "source": str(source),
+            "sha256": sha256,
+        }
+        with self._lock:
+            imports = self.data["imports"]
+            imports.append(entry)
+            del imports[:-MAX_IMPORTS]
+        self.record("import", mod_name, source=str(source))
+
+    def get_imports(self) -> list[dict[str, Any]]:
+        """返回导入记录（最新在前）。"""
+        with self._lock:
+            return [dict(e) for e in reversed(self.data["imports"])]
+
+    def get_import(self, import_id: str) -> dict[str, Any] | None:
+        """按 ID 查找导入记录。"""
+        with self._lock:
+            for entry in self.data["imports"]:
+                if entry.get("id") == import_id:
+                    return dict(entry)
+        return None
+
+    def _stats_entry(self, mod_name: str) -> dict[str, Any]:
+        entry = self.data["stats"].setdefault(mod_name, {})
+        entry.setdefault("install_count", 0)
+        entry.setdefault("active_seconds", 0)
+        entry.setdefault("active_since", None)
+        entry.setdefault("last_installed", None)
+        return entry
+
+    def sync_active(self, active_mods) -> None:
+        """
+        按当前仍在 sound/mod 中拥有文件的语音包更新生效区间：新出现的开始计时，已消失的累加时长。
+
+        Args:
+            active_mods: 当前生效的语音包名称集合（来自安装清单的 file_map）
+        """
+        active_mods = set(active_mods)
+        now = datetime.now()
+        changed = False
+        with self._lock:
+            for mod_name in active_mods:
+                entry = self._stats_entry(mod_name)
+                if not entry["active_since"]:
+                    entry["active_since"] = now.isoformat(timespec="seconds")
+                    changed = True
+            for mod_name, entry in self.data["stats"].items():
+                since = entry.get("active_since")
+                if since and mod_name not in active_mods:
+                    try:
+                        entry["active_seconds"] += max(0, int((now - datetime.fromisoformat(since)).total_seconds()))
+                    except ValueError:
+                        pass
+                    entry["active_since"] = None
+                    changed = True
+            if changed:
+                self._save()
+
+    def get_usage(self, mod_name: str) -> dict[str, Any]:
+        """
+        返回语音包的使用统计。
+
+        Returns:
+            {"install_count", "active_seconds"（含进行中的生效时长）, "active", "last_installed"}
+        """
+        with self._lock:
+            entry = dict(self.data["stats"].get(mod_name) or {})
+        active_seconds = entry.get("active_seconds", 0)
+        since = entry.get("active_since")
+        if since:
+            try:
+                active_seconds += max(0, int((datetime.now() - datetime.fromisoformat(since)).total_seconds()))
+            except ValueError:
+                pass
+        return {
+            "install_count": entry.get("install_count", 0),
+            "active_seconds": active_seconds,
+            "active": bool(since),
+            "last_installed": entry.get("last_installed"),
+        }
+
+    def set_note(self, mod_name: str, text: str) -> bool:
+        """保存语音包备注，空内容表示删除。"""
+        text = str(text or "").strip()[:MAX_NOTE_LENGTH]
+        with self._lock:
+            if text:
+                self.data["notes"][mod_name] = text
+            else:
+                self.data["notes"].pop(mod_name, None)
+            return self._save()
+
+    def get_history(self, mod_name: str) -> dict[str, Any]:
+        """
+        汇总语音包的备注与历史记录。
+
+        Returns:
+            {"note", "imported"（首次导入时间）, "updated"（最近一次重新导入时间，未重新导入为 None）,
+             "install_count", "events"（导入/安装/卸载记录，最新在前）}
+        """
+        with self._lock:
+            note = self.data["notes"].get(mod_name, "")
+            events = [e for e in self.data["events"] if e.get("mod") == mod_name and e.get("action") in HISTORY_ACTIONS]
+        imports = [e["time"] for e in events if e["action"] == "import"]
+        return {
+            "note": note,
+            "imported": imports[0] if imports else None,
+            "updated": imports[-1] if len(imports) > 1 else None,
+            "install_count": self.get_usage(mod_name)["install_count"],
+            "events": events[::-1],
+        }
+
+    def get_recent(self, limit: int = 10) -> dict[str, Any]:
+        """
+        汇总最近活动。
+
+        Returns:
+            {
+                "last_install": 最近一次安装记录（含 files）或 None,
+                "continue": 最近查看但之后未安装的语音包记录或 None,
+                "mods": 最近操作过的语音包（去重，最新在前），每项含 mod、action、time
+            }
+        """
+        with self._lock:
+            events = list(self.data["events"])
+
+        last_install = None
+        continue_item = None
+        installed_after = set()
+        mods = []
+        seen = set()
+        for event in reversed(events):
+            mod = event.get("mod", "")
+            action = event.get("action")
+            if action == "install":
+                if last_install is None:
+                    last_install = event
+                installed_after.add(mod)
+            elif action == "view" and continue_item is None and mod not in installed_after:
+                continue_item = event
+            if mod and mod not in seen and len(mods) < limit:
+                seen.add(mod)
+                mods.append({"mod": mod, "action": action, "time": event.get("time", "")})
+
+        return {"last_install": last_install, "continue": continue_item, "mods": mods}
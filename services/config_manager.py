@@ -60,9 +60,14 @@ class ConfigManager:
         "agreement_version": "",
         "sights_path": "",
         "pending_dir": "",
-        "library_dir": ""
+        "library_dir": "",
+        "install_excludes": {},
+        "pending_rules": []
     }
 
+    # 待解压区导入规则可选的目标：语音包库、涂装、炮镜、跳过
+    PENDING_RULE_TARGETS = ("voice", "skins", "sights", "skip")
+
     def __init__(self):
         """初始化配置管理器，加载或创建配置文件。"""
         self.config_dir = DOCS_DIR
@@ -328,6 +333,61 @@ class ConfigManager:
         self.config["library_dir"] = str(path) if path else ""
         return self.save_config()
 
+    def get_install_excludes(self, mod_name: str) -> list[str]:
+        """读取用户为指定语音包设置的安装排除规则（通配符列表）。"""
+        excludes = self.config.get("install_excludes") or {}
+        return list(excludes.get(mod_name, []))
+
+    def set_install_excludes(self, mod_name: str, patterns: list[str]) -> bool:
+        """
+        更新指定语音包的安装排除规则并写入 settings.json，空列表表示清除。
+        
+        Args:
+            mod_name: 语音包名称
+            patterns: 通配符列表，如 ["*_music_*.bank"]
+            
+        Returns:
+            bool: 是否成功保存
+        """
+        excludes = dict(self.config.get("install_excludes") or {})
+        cleaned = [str(p).strip() for p in patterns or [] if str(p).strip()]
+        if cleaned:
+            excludes[mod_name] = cleaned
+        else:
+            excludes.pop(mod_name, None)
+        self.config["install_excludes"] = excludes
+        return self.save_config()
+
+    def get_pending_rules(self) -> list[dict[str, str]]:
+        """读取待解压区导入规则列表，每项为 {"pattern": 通配符, "target": 目标}。"""
+        rules = self.config.get("pending_rules") or []
+        return [dict(r) for r in rules if isinstance(r, dict)]
+
+    def set_pending_rules(self, rules: list[dict[str, str]]) -> bool:
+        """
+        更新待解压区导入规则并写入 settings.json，按顺序匹配，先匹配者生效。
+        
+        Args:
+            rules: 规则列表，如 [{"pattern": "*skin*", "target": "skins"}]
+            
+        Returns:
+            bool: 是否成功保存
+            
+        Raises:
+            ValueError: 规则缺少通配符或目标无效
+        """
+        cleaned = []
+        for rule in rules or []:
+            pattern = str(rule.get("pattern", "")).strip()
+            target = str(rule.get("target", "")).strip()
+            if not pattern:
+                raise ValueError("规则缺少文件名通配符")
+            if target not in self.PENDING_RULE_TARGETS:
+                raise ValueError(f"无效的导入目标: {target}")
+            cleaned.append({"pattern": pattern, "target": target})
+        self.config["pending_rules"] = cleaned
+        return self.save_config()
+
     def get_telemetry_enabled(self):
         """
         功能定位:
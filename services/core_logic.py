@@ -14,6 +14,9 @@
 - 关键操作支援回滚
 - 异常信息记录完整的上下文
 """
+import errno
+import fnmatch
+import hashlib
 import os
 import shutil
 import threading
@@ -32,11 +35,25 @@ from typing import List, Callable
 
 # 引入安装清单管理器
 from services.manifest_manager import ManifestManager
+from wt.wt_sound import check_bank_file
 from utils.logger import get_logger
 
 log = get_logger(__name__)
 
 
+def is_excluded(file_rel_path: str, patterns: List[str] | None) -> bool:
+    """按通配符判断文件是否被排除，规则同时匹配文件名与相对路径（不区分大小写）。"""
+    if not patterns:
+        return False
+    rel = str(file_rel_path).replace("\\", "/").lower()
+    name = rel.rsplit("/", 1)[-1]
+    for pattern in patterns:
+        pattern = str(pattern).replace("\\", "/").lower()
+        if fnmatch.fnmatchcase(name, pattern) or fnmatch.fnmatchcase(rel, pattern):
+            return True
+    return False
+
+
 class CoreServiceError(Exception):
     """CoreService 相关错误的基类。"""
     pass
@@ -63,6 +80,7 @@ class CoreService:
     属性:
         game_root: 游戏根目录路径
         manifest_mgr: 安装清单管理器
+        last_report: 最近一次安装/还原的操作记录，用于生成操作报告
     """
     
     def __init__(self):
@@ -70,6 +88,33 @@ class CoreService:
         self.game_root: Path | None = None
         # 安装清单管理器在 validate_game_path 校验通过后初始化
         self.manifest_mgr: ManifestManager | None = None
+        self.last_report: dict | None = None
+
+    def _new_report(self, operation: str, mod_name: str = "") -> dict:
+        # 创建操作记录：安装/还原过程中逐项填充，结束时写入耗时与结果
+        report = {
+            "operation": operation,
+            "mod": mod_name,
+            "game_root": str(self.game_root or ""),
+            "started": time.strftime("%Y-%m-%d %H:%M:%S"),
+            "_t0": time.monotonic(),
+            "duration_seconds": 0.0,
+            "success": False,
+            "error": "",
+            "copied": [],
+            "unchanged": [],
+            "excluded": [],
+            "skipped": [],
+            "conflicts": [],
+            "removed": [],
+            "warnings": [],
+        }
+        self.last_report = report
+        return report
+
+    @staticmethod
+    def _finish_report(report: dict) -> None:
+        report["duration_seconds"] = round(time.monotonic() - report.pop("_t0", time.monotonic()), 2)
 
     def validate_game_path(self, path_str: str) -> tuple[bool, str]:
         """
@@ -111,6 +156,28 @@ class CoreService:
         
         return True, "校验通过"
 
+    # 游戏目录下记录客户端版本号的文件，按顺序尝试
+    GAME_VERSION_FILES = ("content/pkg_main.ver", "game.ver")
+
+    def get_game_version(self) -> str:
+        """
+        读取当前游戏目录中的 War Thunder 版本号（如 2.41.0.37），用于遥测中关联游戏更新与语音包失效问题。
+
+        Returns:
+            版本号字符串，未设置游戏路径或无法识别时返回空字符串
+        """
+        if not self.game_root:
+            return ""
+        for rel in self.GAME_VERSION_FILES:
+            try:
+                content = (self.game_root / rel).read_text(encoding="utf-8", errors="ignore")
+            except OSError:
+                continue
+            match = re.search(r"\d+(?:\.\d+){2,3}", content)
+            if match:
+                return match.group(0)
+        return ""
+
     def start_search_thread(self, callback: Callable[[str | None], None]) -> None:
         """
         以后台线程执行 auto_detect_game_path，并在完成后回调返回结果。
@@ -349,6 +416,26 @@ class CoreService:
             log.debug(f"路径安全检查异常: {e}")
             return False
 
+    @staticmethod
+    def _file_digest(path: Path) -> str:
+        h = hashlib.sha256()
+        with open(path, "rb") as f:
+            for chunk in iter(lambda: f.read(1024 * 1024), b""):
+                h.update(chunk)
+        return h.hexdigest()
+
+    def _is_same_file(self, src: Path, dest: Path) -> bool:
+        """
+        判断目标文件是否与源文件内容一致（先比较大小，大小相同再比较 SHA-256），
+        用于重新安装同一语音包时跳过未变化的文件。
+        """
+        try:
+            if not dest.is_file() or src.stat().st_size != dest.stat().st_size:
+                return False
+            return self._file_digest(src) == self._file_digest(dest)
+        except OSError:
+            return False
+
     def _remove_path(self, path_obj: Path) -> None:
         """
         删除文件或目录（包含只读文件的处理），用于清理 sound/mod 下的子项。
@@ -384,6 +471,57 @@ class CoreService:
             log.error(f"删除路径失败: {p} - {type(e).__name__}: {e}")
             raise
 
+    # 写入权限预检的目标目录 (名称, 相对游戏根目录的路径)
+    WRITE_CHECK_TARGETS = (("sound/mod", ("sound", "mod")), ("UserSkins", ("UserSkins",)))
+
+    def check_write_access(self, targets: tuple[str, ...] | None = None) -> list[dict]:
+        """
+        在 sound/mod 与 UserSkins 中尝试写入并删除一个临时文件，判断是否可以安装。
+        目录尚不存在时对游戏目录内最近的已存在上级目录进行检测。
+        
+        Args:
+            targets: 仅检测指定名称的目录，为空时检测全部
+            
+        Returns:
+            [{"target", "path", "ok", "reason", "hint"}]，reason 取值 permission/read_only/cloud/error
+        """
+        if not self.game_root:
+            raise GamePathError("未设置游戏路径")
+
+        results = []
+        for name, parts in self.WRITE_CHECK_TARGETS:
+            if targets and name not in targets:
+                continue
+            path = self.game_root.joinpath(*parts)
+            probe_dir = path
+            while not probe_dir.exists() and probe_dir != self.game_root:
+                probe_dir = probe_dir.parent
+
+            entry = {"target": name, "path": str(path), "ok": True, "reason": "", "hint": ""}
+            probe = probe_dir / f".aimerwt_write_test_{os.getpid()}"
+            try:
+                probe.write_bytes(b"")
+                probe.unlink()
+            except OSError as e:
+                entry["ok"] = False
+                entry["reason"], entry["hint"] = self._classify_write_error(e, path)
+                log.warning(f"[CHECK] {name} 不可写入: {e}")
+            results.append(entry)
+        return results
+
+    @staticmethod
+    def _classify_write_error(e: OSError, path: Path) -> tuple[str, str]:
+        """将写入失败的异常归类为可操作的提示。"""
+        winerror = getattr(e, "winerror", None) or 0
+        # 362-398 为 Windows ERROR_CLOUD_FILE_* 系列错误码（OneDrive 等云同步占用或未同步）
+        if 362 <= winerror <= 398 or "onedrive" in str(path).lower():
+            return "cloud", "游戏目录位于云同步文件夹中，请暂停同步或将游戏移出 OneDrive 等同步目录"
+        if e.errno == errno.EROFS or winerror == 19:
+            return "read_only", "磁盘处于只读状态，请检查磁盘写保护或挂载方式"
+        if isinstance(e, PermissionError) or winerror == 5:
+            return "permission", "权限不足，请以管理员身份运行本程序，或将游戏移出 Program Files 等受保护目录"
+        return "error", f"写入失败: {e}"
+
     def get_installed_mods(self) -> List[str]:
         """
         获取已安装的 mod 列表。
@@ -426,7 +564,8 @@ class CoreService:
         self, 
         source_mod_path: Path, 
         install_list: List[str] | None = None, 
-        progress_callback: Callable[[int, str], None] | None = None
+        progress_callback: Callable[[int, str], None] | None = None,
+        exclude_patterns: List[str] | None = None
     ) -> bool:
         """
         将语音包库中的文件複製到游戏目录 <game_root>/sound/mod，并更新 config.blk 以启用 mod。
@@ -435,10 +574,12 @@ class CoreService:
             source_mod_path: 语音包源目录路径
             install_list: 待安装的文件夹相对路径列表
             progress_callback: 进度回调函数 (百分比, 讯息)
+            exclude_patterns: 排除规则（通配符），匹配的文件不安装
             
         Returns:
             是否安装成功
         """
+        report = self._new_report("install", source_mod_path.name)
         try:
             log.info(f"[INSTALL] 准备安装: {source_mod_path.name}")
 
@@ -475,6 +616,14 @@ class CoreService:
                     progress_callback(100, "未选择文件")
                 return False
 
+            # 按排除规则过滤（来自语音包 info.json 的 exclude 与用户设置）
+            if exclude_patterns:
+                kept = [p for p in install_list if not is_excluded(p, exclude_patterns)]
+                report["excluded"] = [p for p in install_list if is_excluded(p, exclude_patterns)]
+                if len(kept) != len(install_list):
+                    log.info(f"[SKIP] 按排除规则跳过 {len(install_list) - len(kept)} 个文件")
+                install_list = kept
+
             # 统计总文件数
             total_files_to_copy = len(install_list)
 
@@ -488,6 +637,7 @@ class CoreService:
                 progress_callback(15, f"共 {total_files_to_copy} 个文件待安装")
 
             total_files = 0
+            unchanged_files = 0
             # 收集本次安装的目标文件名，用于写入安装清单
             installed_files_record = []
 
@@ -506,9 +656,27 @@ class CoreService:
 
                     if not src_file.exists():
                         log.warning(f"[WARN] 源文件不存在: {file_rel_path}")
+                        report["skipped"].append({"file": file_rel_path, "reason": "源文件不存在"})
                         continue
-                    shutil.copy2(src_file, dest_file)
-                    total_files += 1
+                    if src_file.name.lower().endswith(".bank"):
+                        reason = check_bank_file(src_file)
+                        if reason:
+                            log.warning(f"[WARN] 跳过损坏的音频库文件 {file_rel_path}: {reason}")
+                            report["skipped"].append({"file": file_rel_path, "reason": reason})
+                            continue
+                    previous_owner = ""
+                    if self.manifest_mgr:
+                        previous_owner = self.manifest_mgr.manifest.get("file_map", {}).get(dest_file.name, "")
+                    # 目标文件内容未变化时跳过複製，但仍记入安装清单
+                    if self._is_same_file(src_file, dest_file):
+                        unchanged_files += 1
+                        report["unchanged"].append(dest_file.name)
+                    else:
+                        shutil.copy2(src_file, dest_file)
+                        total_files += 1
+                        report["copied"].append(dest_file.name)
+                    if previous_owner and previous_owner != source_mod_path.name:
+                        report["conflicts"].append({"file": dest_file.name, "previous_owner": previous_owner})
                     installed_files_record.append(dest_file.name)
 
                     # 更新进度 (限制更新频率，避免 UI 卡顿)
@@ -525,20 +693,26 @@ class CoreService:
 
                 except PermissionError as e:
                     log.warning(f"複製文件 {src_file.name} 失败（权限不足）: {e}")
+                    report["warnings"].append(f"複製文件 {src_file.name} 失败（权限不足）: {e}")
                 except OSError as e:
                     log.warning(f"複製文件 {src_file.name} 失败: {e}")
+                    report["warnings"].append(f"複製文件 {src_file.name} 失败: {e}")
                 except Exception as e:
                     log.warning(f"複製文件 {src_file.name} 失败: {type(e).__name__}: {e}")
+                    report["warnings"].append(f"複製文件 {src_file.name} 失败: {type(e).__name__}: {e}")
 
             log.info(f"已成功安装 {total_files} 个文件")
+            if unchanged_files:
+                log.info(f"[SKIP] {unchanged_files} 个文件与游戏目录中的内容一致，已跳过复制")
 
             # 写入安装清单记录（mod -> 文件名列表）
-            if self.manifest_mgr and total_files > 0:
+            if self.manifest_mgr and installed_files_record:
                 try:
                     self.manifest_mgr.record_installation(source_mod_path.name, installed_files_record)
                     log.info("已更新安装清单记录")
                 except Exception as e:
                     log.warning(f"更新清单失败: {e}")
+                    report["warnings"].append(f"更新清单失败: {e}")
 
             if progress_callback:
                 progress_callback(95, "更新游戏配置...")
@@ -550,19 +724,24 @@ class CoreService:
                 progress_callback(100, "安装完成")
 
             log.info(f"[SUCCESS] [DONE] 安装完成！本次复盖/新增 {total_files} 个文件。")
+            report["success"] = True
             return True
 
         except (GamePathError, InstallError) as e:
             log.error(f"安装过程错误: {e}")
+            report["error"] = str(e)
             if progress_callback:
                 progress_callback(100, "安装失败")
             return False
         except Exception as e:
             log.error(f"安装过程严重错误: {type(e).__name__}: {e}")
             log.exception("安装异常详情")
+            report["error"] = f"{type(e).__name__}: {e}"
             if progress_callback:
                 progress_callback(100, "安装失败")
             return False
+        finally:
+            self._finish_report(report)
 
     def restore_game(self) -> bool:
         """
@@ -576,6 +755,7 @@ class CoreService:
         Returns:
             是否还原成功
         """
+        report = self._new_report("restore")
         try:
             log.info("[RESTORE] 正在还原纯淨模式...")
             
@@ -592,13 +772,17 @@ class CoreService:
                         # 删除前进行边界校验，确保删除目标位于 sound/mod 目录内部
                         if not self._is_safe_deletion_path(item):
                             log.warning(f"🚫 [安全拦截] 拒绝删除保护文件: {item}")
+                            report["warnings"].append(f"拒绝删除保护文件: {item}")
                             continue
 
                         self._remove_path(item)
+                        report["removed"].append(item.name)
                     except PermissionError as e:
                         log.warning(f"无法删除 {item.name}（权限不足）: {e}")
+                        report["warnings"].append(f"无法删除 {item.name}（权限不足）: {e}")
                     except OSError as e:
                         log.warning(f"无法删除 {item.name}: {e}")
+                        report["warnings"].append(f"无法删除 {item.name}: {e}")
             
             # 清空安装清单记录
             if self.manifest_mgr:
@@ -606,17 +790,83 @@ class CoreService:
                     self.manifest_mgr.clear_manifest()
                 except Exception as e:
                     log.warning(f"清空清单失败: {e}")
+                    report["warnings"].append(f"清空清单失败: {e}")
 
             self._disable_config_mod()
             log.info("[SUCCESS] 还原成功！所有 Mod 已清空，配置文件已重置。")
+            report["success"] = True
             return True
             
         except GamePathError as e:
             log.error(f"还原失败: {e}")
+            report["error"] = str(e)
             return False
         except Exception as e:
             log.error(f"还原失败: {type(e).__name__}: {e}")
             log.exception("还原异常详情")
+            report["error"] = f"{type(e).__name__}: {e}"
+            return False
+        finally:
+            self._finish_report(report)
+
+    def uninstall_mod(self, mod_name: str) -> bool:
+        """
+        按安装清单卸载单个语音包，只删除清单中仍归属于该语音包的文件。
+        
+        操作包括：
+        - 删除 file_map 中归属于该语音包的 sound/mod 文件
+        - 移除该语音包的安装记录
+        - 没有剩余已安装语音包时关闭 config.blk 的 enable_mod
+        
+        Args:
+            mod_name: 语音包名称
+            
+        Returns:
+            是否卸载成功
+        """
+        try:
+            log.info(f"[UNINSTALL] 正在卸载: {mod_name}")
+
+            if not self.game_root:
+                raise GamePathError("未设置游戏路径")
+            if not self.manifest_mgr:
+                raise GamePathError("安装清单未初始化")
+
+            mod_dir = self.game_root / "sound" / "mod"
+            file_map = self.manifest_mgr.manifest.get("file_map", {})
+            owned = [name for name, owner in file_map.items() if owner == mod_name]
+
+            removed = 0
+            for file_name in owned:
+                target = mod_dir / file_name
+                if not self._is_safe_deletion_path(target):
+                    log.warning(f"🚫 [安全拦截] 拒绝删除保护文件: {target}")
+                    continue
+                if not target.exists():
+                    continue
+                try:
+                    self._remove_path(target)
+                    removed += 1
+                except PermissionError as e:
+                    log.warning(f"无法删除 {file_name}（权限不足）: {e}")
+                except OSError as e:
+                    log.warning(f"无法删除 {file_name}: {e}")
+
+            if not self.manifest_mgr.remove_mod_record(mod_name):
+                return False
+
+            if not self.manifest_mgr.manifest.get("installed_mods"):
+                self._disable_config_mod()
+
+            log.info(f"[SUCCESS] 已卸载 {mod_name}，删除 {removed} 个文件。")
+            return True
+
+        except GamePathError as e:
+            log.error(f"卸载失败: {e}")
+            return False
+        except Exception as e:
+            log.error(f"卸载失败: {type(e).__name__}: {e}")
+            log.exception("卸载异常详情")
             return False
 
     def _update_config_blk(self) -> bool:
@@ -0,0 +1,241 @@
+# -*- coding: utf-8 -*-
+"""
+游戏运行监控模组：在程序运行期间轮询游戏进程（aces.exe）与游戏路径状态。
+
+功能包括：
+- 检测游戏进程是否正在运行
+- 记录每次游戏会话的开始/结束时间，统计近 7 天游玩时长
+- 定期复查已配置的游戏路径（磁盘被移除、目录被移动、config.blk 被删除）
+- 状态变化时回调通知（供前端提示、安装前拦截、提示重新搜索路径）
+
+会话记录存储于应用数据目录的 playtime.json，仅保存在本机。
+"""
+import json
+import platform
+import subprocess
+import threading
+from datetime import datetime, timedelta
+from pathlib import Path
+from typing import Any, Callable
+
+from utils.logger import get_logger
+from utils.utils import get_docs_data_dir
+
+log = get_logger(__name__)
+
+# 游戏主程序进程名（Windows 为 aces.exe，Linux/macOS 为 aces）
+GAME_PROCESS_NAMES = ("aces.exe", "aces")
+
+# 轮询间隔（秒）
+POLL_INTERVAL = 15
+
+# 游戏路径复查间隔（秒）
+PATH_CHECK_INTERVAL = 30
+
+# 保留的会话记录条数上限
+MAX_SESSIONS = 50
+
+
+def is_game_running() -> bool:
+    """检测游戏进程是否正在运行；检测命令不可用时视为未运行。"""
+    try:
+        if platform.system() == "Windows":
+            result = subprocess.run(
+                ["tasklist", "/FI", f"IMAGENAME eq {GAME_PROCESS_NAMES[0]}", "/NH", "/FO", "CSV"],
+                capture_output=True,
+                text=True,
+                errors="ignore",
+                creationflags=getattr(subprocess, "CREATE_NO_WINDOW", 0),
+            )
+            return f'"{GAME_PROCESS_NAMES[0]}"' in result.stdout.lower()
+        result = subprocess.run(["pgrep", "-x", GAME_PROCESS_NAMES[1]], capture_output=True)
+        return result.returncode == 0
+    except (OSError, subprocess.SubprocessError) as e:
+        log.debug(f"检测游戏进程失败: {e}")
+        return False
+
+
+class GameMonitor:
+    """
+    后台轮询游戏进程，维护当前运行状态与会话记录。
+
+    属性:
+        data_file: 会话记录文件路径
+        running: 游戏当前是否在运行
+        since: 本次会话开始时间（未运行时为 None）
+    """
+
+    def __init__(self, data_file: Path | str | None = None,
+                 on_change: Callable[[dict[str, Any]], None] | None = None):
+        self.data_file = Path(data_file) if data_file else get_docs_data_dir() / "playtime.json"
+        self.on_change = on_change
+        self.running = False
+        self.since = None
+        self._lock = threading.Lock()
+        self._stop = threading.Event()
+        self._thread = None
+        self.sessions = self._load()
+
+    def _load(self) -> list[dict[str, Any]]:
+        if not self.data_file.exists():
+            return []
+        try:
+            with open(self.data_file, "r", encoding="utf-8") as f:
+                data = json.load(f)
+            sessions = data.get("sessions") if isinstance(data, dict) else None
+            return sessions if isinstance(sessions, list) else []
+        except Exception as e:
+            log.warning(f"读取游玩记录失败: {type(e).__name__}: {e}")
+            return []
+
+    def _save(self) -> None:
+        try:
+            self.data_file.parent.mkdir(parents=True, exist_ok=True)
+            temp_file = self.data_file.with_suffix(".tmp")
+            with open(temp_file, "w", encoding="utf-8") as f:
+                json.dump({"sessions": self.sessions}, f, indent=2, ensure_ascii=False)
+            temp_file.replace(self.data_file)
+        except Exception as e:
+            log.warning(f"保存游玩记录失败: {type(e).__name__}: {e}")
+
+    def start(self) -> None:
+        """启动后台轮询线程（重复调用无副作用）。"""
+        if self._thread and self._thread.is_alive():
+            return
+        self._stop.clear()
+        self._thread = threading.Thread(target=self._run, daemon=True)
+        self._thread.start()
+
+    def stop(self) -> None:
+        """停止轮询；游戏仍在运行时结束并保存当前会话。"""
+        self._stop.set()
+        self.update(False)
+
+    def _run(self) -> None:
+        while not self._stop.is_set():
+            self.update(is_game_running())
+            self._stop.wait(POLL_INTERVAL)
+
+    def update(self, running: bool) -> None:
+        """根据最新检测结果更新状态，游戏启动/退出时记录会话并触发回调。"""
+        with self._lock:
+            if running == self.running:
+                return
+            now = datetime.now()
+            if running:
+                self.since = now
+                log.info("[GAME] 检测到游戏已启动")
+            else:
+                if self.since:
+                    self.sessions.append({
+                        "start": self.since.isoformat(timespec="seconds"),
+                        "end": now.isoformat(timespec="seconds"),
+                        "seconds": int((now - self.since).total_seconds()),
+                    })
+                    del self.sessions[:-MAX_SESSIONS]
+                    self._save()
+                self.since = None
+                log.info("[GAME] 检测到游戏已退出")
+            self.running = running
+        if self.on_change:
+            try:
+                self.on_change(self.get_status())
+            except Exception as e:
+                log.debug(f"游戏状态回调失败: {e}")
+
+    def get_status(self) -> dict[str, Any]:
+        """
+        返回当前运行状态与游玩统计。
+
+        Returns:
+            {"running", "since", "current_seconds", "recent_sessions", "week_seconds"}
+        """
+        with self._lock:
+            sessions = list(self.sessions)
+            since = self.since
+            running = self.running
+        now = datetime.now()
+        week_ago = (now - timedelta(days=7)).isoformat(timespec="seconds")
+        current_seconds = int((now - since).total_seconds()) if since else 0
+        week_seconds = sum(s.get("seconds", 0) for s in sessions if s.get("end", "") >= week_ago)
+        return {
+            "running": running,
+            "since": since.isoformat(timespec="seconds") if since else None,
+            "current_seconds": current_seconds,
+            "recent_sessions": sessions[-10:][::-1],
+            "week_seconds": week_seconds + current_seconds,
+        }
+
+
+def check_game_path(path_str: str) -> dict[str, Any]:
+    """
+    检查游戏路径当前是否可用。
+
+    Returns:
+        {"ok", "path", "reason"（unset/drive_missing/dir_missing/config_missing）, "message"}
+    """
+    if not path_str:
+        return {"ok": False, "path": "", "reason": "unset", "message": "尚未设置游戏路径"}
+    path = Path(path_str)
+    try:
+        if not path.is_dir():
+            if path.anchor and not Path(path.anchor).exists():
+                return {"ok": False, "path": path_str, "reason": "drive_missing",
+                        "message": f"游戏所在磁盘 {path.anchor} 不可用，可能已被移除"}
+            return {"ok": False, "path": path_str, "reason": "dir_missing",
+                    "message": "游戏目录不存在，可能已被移动或卸载"}
+        if not (path / "config.blk").is_file():
+            return {"ok": False, "path": path_str, "reason": "config_missing",
+                    "message": "游戏目录中缺少 config.blk"}
+    except OSError as e:
+        return {"ok": False, "path": path_str, "reason": "dir_missing", "message": f"无法访问游戏目录: {e}"}
+    return {"ok": True, "path": path_str, "reason": "", "message": "游戏路径有效"}
+
+
+class GamePathMonitor:
+    """
+    后台定期复查已配置的游戏路径，状态变化（失效或恢复）时回调通知。
+
+    属性:
+        status: 最近一次检查结果（尚未检查时为 None）
+    """
+
+    def __init__(self, path_provider: Callable[[], str],
+                 on_change: Callable[[dict[str, Any]], None] | None = None):
+        self.path_provider = path_provider
+        self.on_change = on_change
+        self.status = None
+        self._stop = threading.Event()
+        self._thread = None
+
+    def start(self) -> None:
+        """启动后台复查线程（重复调用无副作用）。"""
+        if self._thread and self._thread.is_alive():
+            return
+        self._stop.clear()
+        self._thread = threading.Thread(target=self._run, daemon=True)
+        self._thread.start()
+
+    def stop(self) -> None:
+        self._stop.set()
+
+    def _run(self) -> None:
+        while not self._stop.wait(PATH_CHECK_INTERVAL):
+            self.check()
+
+    def check(self) -> dict[str, Any]:
+        """立即检查一次；与上次结果相比可用性或路径发生变化时触发回调。"""
+        status = check_game_path(self.path_provider())
+        previous = self.status
+        self.status = status
+        # 首次检查只记录基线，启动时的路径问题由启动自检报告
+        if previous is not None and (previous["ok"], previous["path"], previous["reason"]) != (
+                status["ok"], status["path"], status["reason"]):
+            if not status["ok"]:
+                log.warning(f"[GAME] 游戏路径已失效: {status['message']}，请重新搜索或选择游戏目录")
+            if self.on_change:
+                try:
+                    self.on_change(status)
+                except Exception as e:
+                    log.debug(f"游戏路径状态回调失败: {e}")
+        return status
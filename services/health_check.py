@@ -0,0 +1,138 @@
+# -*- coding: utf-8 -*-
+"""
+启动自检模组：程序启动时检查关键状态，生成结构化的健康报告供前端提示。
+
+检查项：
+- config: settings.json 可读取且格式正确
+- data_dirs: 应用数据目录、待解压区、语音包库可写入
+- game_path: 已设置的游戏路径仍然有效
+- manifest: 游戏目录中的安装清单可解析
+- themes: 主题目录可访问，当前选择的主题文件存在
+
+每项结果包含 level（ok/warning/error）、说明与建议操作，问题在启动时即可提示，而不是在安装或导入中途失败。
+"""
+import json
+import tempfile
+from pathlib import Path
+
+from utils.logger import get_logger
+from utils.utils import get_docs_data_dir
+
+log = get_logger(__name__)
+
+CHECK_NAMES = {
+    "config": "设置文件",
+    "data_dirs": "数据目录",
+    "game_path": "游戏路径",
+    "manifest": "安装清单",
+    "themes": "主题",
+}
+
+
+def _result(key: str, level: str, message: str, action: str = "") -> dict:
+    return {"key": key, "name": CHECK_NAMES[key], "level": level, "message": message, "action": action}
+
+
+def _is_writable(path: Path) -> bool:
+    try:
+        path.mkdir(parents=True, exist_ok=True)
+        with tempfile.TemporaryFile(dir=path):
+            pass
+        return True
+    except OSError:
+        return False
+
+
+def _check_config(cfg_mgr) -> dict:
+    config_file = Path(cfg_mgr.get_config_file_path())
+    if not config_file.exists():
+        return _result("config", "ok", "尚未保存设置，使用默认设置")
+    if not isinstance(cfg_mgr._load_json_with_fallback(config_file), dict):
+        return _result("config", "error", f"设置文件无法读取或已损坏，当前使用默认设置: {config_file}",
+                       "保存任意设置会重新生成设置文件")
+    return _result("config", "ok", "设置文件正常")
+
+
+def _check_data_dirs(lib_mgr) -> dict:
+    dirs = {
+        "应用数据目录": get_docs_data_dir(),
+        "待解压区": lib_mgr.pending_dir,
+        "语音包库": lib_mgr.library_dir,
+    }
+    failed = [f"{name} ({path})" for name, path in dirs.items() if not _is_writable(Path(path))]
+    if failed:
+        return _result("data_dirs", "error", "以下目录无法写入: " + "；".join(failed),
+                       "检查目录权限或在设置中更换待解压区/语音包库路径")
+    return _result("data_dirs", "ok", "数据目录可写入")
+
+
+def _check_game_path(cfg_mgr, core) -> dict:
+    path = cfg_mgr.get_game_path()
+    if not path:
+        return _result("game_path", "warning", "尚未设置游戏路径", "在主页自动搜索或手动选择游戏目录")
+    valid, msg = core.validate_game_path(path)
+    if not valid:
+        return _result("game_path", "error", f"游戏路径已失效: {path}（{msg}）", "重新搜索或选择游戏目录")
+    return _result("game_path", "ok", f"游戏路径有效: {path}")
+
+
+def _check_manifest(core, game_path_ok: bool) -> dict:
+    if not game_path_ok or not core.manifest_mgr:
+        return _result("manifest", "ok", "未设置有效游戏路径，跳过检查")
+    manifest_file = core.manifest_mgr.manifest_file
+    if not manifest_file.exists():
+        return _result("manifest", "ok", "尚无安装记录")
+    try:
+        with open(manifest_file, "r", encoding="utf-8") as f:
+            data = json.load(f)
+        if not isinstance(data, dict):
+            raise ValueError("格式无效")
+    except (OSError, ValueError) as e:
+        return _result("manifest", "error", f"安装清单无法解析，卸载与冲突检测将不可用: {e}",
+                       "执行一次还原纯净模式可重建安装清单")
+    return _result("manifest", "ok", f"安装清单正常（{len(data.get('installed_mods', {}))} 个语音包）")
+
+
+def _check_themes(cfg_mgr, themes_dir: Path) -> dict:
+    try:
+        if not themes_dir.is_dir():
+            return _result("themes", "warning", f"主题目录不存在: {themes_dir}", "重新安装程序以恢复主题文件")
+        next(themes_dir.iterdir(), None)
+    except OSError as e:
+        return _result("themes", "warning", f"主题目录无法访问: {e}", "检查程序目录权限")
+    active = cfg_mgr.get_active_theme()
+    if active and not (themes_dir / active).is_file():
+        return _result("themes", "warning", f"当前主题文件不存在: {active}，将使用默认主题", "在设置中重新选择主题")
+    return _result("themes", "ok", "主题目录正常")
+
+
+def run_health_check(cfg_mgr, lib_mgr, core, themes_dir: Path) -> dict:
+    """
+    执行全部启动检查。
+
+    Args:
+        cfg_mgr: ConfigManager 实例
+        lib_mgr: LibraryManager 实例
+        core: CoreService 实例（会按配置的游戏路径初始化安装清单）
+        themes_dir: 主题目录
+
+    Returns:
+        {"ok": 是否没有 error 级问题, "checks": [{"key", "name", "level", "message", "action"}]}
+    """
+    checks = []
+    for key, func in (
+        ("config", lambda: _check_config(cfg_mgr)),
+        ("data_dirs", lambda: _check_data_dirs(lib_mgr)),
+        ("game_path", lambda: _check_game_path(cfg_mgr, core)),
+        ("manifest", lambda: _check_manifest(core, checks[-1]["level"] == "ok")),
+        ("themes", lambda: _check_themes(cfg_mgr, Path(themes_dir))),
+    ):
+        try:
+            checks.append(func())
+        except Exception as e:
+            checks.append(_result(key, "error", f"检查失败: {type(e).__name__}: {e}"))
+
+    for item in checks:
+        if item["level"] != "ok":
+            log.warning(f"[HEALTH] {item['name']}: {item['message']}")
+    return {"ok": all(item["level"] != "error" for item in checks), "checks": checks}
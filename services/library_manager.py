@@ -20,19 +20,22 @@ import shutil
 import subprocess
 import time
 import zipfile
+import fnmatch
+import hashlib
 import json
 import re
 from pathlib import Path
 from typing import Any
 from utils.logger import get_logger
 from utils.utils import get_app_data_dir
-from wt.wt_sound import VoiceType, Country
+from wt.wt_sound import VoiceType, Country, COVERAGE_CATEGORIES, VOICE_TYPE_CATEGORY, check_bank_file
 
 log = get_logger(__name__)
 
 # 定义标准文件夹名称
 DIR_PENDING = "../WT待解压区"
 DIR_LIBRARY = "../WT语音包库"
+DIR_QUARANTINE = "校验失败隔离区"
 
 
 # 定义压缩包相关异常类
@@ -61,6 +64,11 @@ class ArchiveExtractionError(ArchiveError):
     pass
 
 
+class ArchiveChecksumMismatch(ArchiveError):
+    """压缩包与校验文件 (.sha256/.md5) 不一致。"""
+    pass
+
+
 class DiskSpaceError(Exception):
     """磁盘空间不足。"""
     pass
@@ -85,6 +93,8 @@ class LibraryManager:
         self._details_cache = {}  # 缓存单个 mod 的详情
         self._scan_cache = None  # 缓存整个扫描结果
         self._last_scan_mtime = 0
+        # 导入成功回调 (mod_name, archive_path)，由上层用于记录导入历史
+        self.import_callback = None
 
         # 初始化待解压区与语音包库目录路径
         # 支援自定义路径，若未提供则使用预设值
@@ -322,6 +332,27 @@ class LibraryManager:
             log.error(f"扫描待解压区失败: {type(e).__name__}: {e}")
         return archives
 
+    @staticmethod
+    def match_pending_rule(file_name: str, rules: list[dict[str, str]] | None) -> str:
+        """
+        按导入规则判断待解压区文件的去向，规则按顺序匹配文件名（不区分大小写），未命中时导入语音包库。
+        
+        Returns:
+            "voice" / "skins" / "sights" / "skip"
+        """
+        name = str(file_name).lower()
+        for rule in rules or []:
+            if fnmatch.fnmatchcase(name, str(rule.get("pattern", "")).lower()):
+                return rule.get("target") or "voice"
+        return "voice"
+
+    def route_pending(self, rules: list[dict[str, str]] | None) -> dict[str, list[Path]]:
+        """按导入规则将待解压区中的压缩包分组，返回 {目标: [压缩包路径]}。"""
+        routes = {}
+        for archive in self.scan_pending():
+            routes.setdefault(self.match_pending_rule(archive.name, rules), []).append(archive)
+        return routes
+
     def _normalize_wtlive_compat_files(self, mod_dir: Path) -> None:
         """
         规范化语音包目录中的元数据与封面文件命名。
@@ -447,6 +478,7 @@ class LibraryManager:
             "link_video": "",
             "tags": [],  # 存储标籤列表 ["tank", "radio"]
             "language": [],  # 存储语言列表 ["中", "美"]
+            "exclude": [],  # 安装时排除的文件通配符 ["*_music_*.bank"]
             "size_str": "0 MB",
             "cover_path": None,
             "capabilities": {}  # 兼容前端旧逻辑
@@ -496,7 +528,7 @@ class LibraryManager:
                 data = self._load_json_with_fallback(found_info_file)
                 if isinstance(data, dict):
                     for key in ["title", "author", "version", "date", "note", "link_bilibili", "link_wtlive",
-                                "link_video", "tags", "language"]:
+                                "link_video", "tags", "language", "exclude"]:
                         if key in data:
                             details[key] = data[key]
                 else:
@@ -785,6 +817,189 @@ class LibraryManager:
 
         return sorted(final_list, key=lambda x: x["type"])
 
+    def check_bank_files(self, mod_dir):
+        """
+        校验目录下可识别语音类型的 .bank 文件的文件头，返回 [{"file": 相对路径, "reason": 问题描述}]。
+        WTLive 伪装的信息/封面文件（info.bank、cover.bank 等）不是音频库，不参与校验。
+        """
+        mod_dir = Path(mod_dir)
+        problems = []
+        for f in sorted(mod_dir.rglob("*")):
+            if not f.is_file() or not f.name.lower().endswith(".bank"):
+                continue
+            if not self.match_voice_type(f.name.lower()):
+                continue
+            reason = check_bank_file(f)
+            if reason:
+                rel_path = str(f.relative_to(mod_dir)).replace("\\", "/")
+                problems.append({"file": rel_path, "reason": reason})
+        return problems
+
+    def _report_bank_problems(self, mod_dir):
+        # 导入完成后提示损坏或截断的 .bank 文件，这些文件在安装时会被跳过
+        problems = self.check_bank_files(mod_dir)
+        for p in problems:
+            self.log(f"[WARN] 音频库文件异常: {p['file']} ({p['reason']})", "WARN")
+        if problems:
+            self.log(f"[WARN] 共 {len(problems)} 个 .bank 文件异常，可能是下载不完整，请重新下载该语音包", "WARN")
+
+    def get_bank_files(self, mod_name):
+        """
+        返回语音包内可安装的 .bank 文件相对路径列表（与安装清单格式一致）。
+        """
+        mod_dir = self.library_dir / str(mod_name)
+        if not self._is_safe_path(mod_dir, self.library_dir) or not mod_dir.is_dir():
+            return []
+        files = []
+        for group in self._detect_mod_files(mod_dir):
+            files.extend(group["files"])
+        return files
+
+    def get_coverage_report(self, mod_name):
+        """
+        根据语音包内 .bank 文件名推断其覆盖的游戏内容类别与国籍。
+        返回格式: {"categories": [{"key": "ground", "name": "陆战", "types": [...], "nations": [...], "missing_types": [...]}],
+                  "missing": ["海战"], "summary": "陆战: 俄罗斯, 德国；未覆盖: 海战"}
+        """
+        mod_dir = self.library_dir / str(mod_name)
+        if not self._is_safe_path(mod_dir, self.library_dir) or not mod_dir.is_dir():
+            raise FileNotFoundError(f"语音包不存在: {mod_name}")
+
+        found = {}
+        for f in mod_dir.rglob("*"):
+            if not f.is_file() or not f.name.lower().endswith(".bank"):
+                continue
+            matched = self.match_voice_type(f.name.lower())
+            if not matched:
+                continue
+            v_type, v_country, _ = matched
+            category = VOICE_TYPE_CATEGORY.get(v_type)
+            if not category:
+                continue
+            entry = found.setdefault(category, {"types": set(), "nations": set()})
+            entry["types"].add(v_type)
+            if v_country:
+                entry["nations"].add(v_country)
+
+        categories = []
+        missing = []
+        summary_parts = []
+        for key, name in COVERAGE_CATEGORIES.items():
+            all_types = [t for t, c in VOICE_TYPE_CATEGORY.items() if c == key]
+            entry = found.get(key)
+            if not entry:
+                missing.append(name)
+                continue
+            nations = sorted(entry["nations"], key=lambda c: c.code)
+            categories.append({
+                "key": key,
+                "name": name,
+                "types": [t.chinese_name for t in all_types if t in entry["types"]],
+                "missing_types": [t.chinese_name for t in all_types if t not in entry["types"]],
+                "nations": [{"code": c.code, "name": c.chinese_name or c.code} for c in nations],
+            })
+            if nations:
+                nation_names = ", ".join(c.chinese_name or c.code for c in nations)
+            else:
+                nation_names = "不区分国籍"
+            summary_parts.append(f"{name}: {nation_names}")
+
+        if missing:
+            summary_parts.append("未覆盖: " + ", ".join(missing))
+        return {
+            "categories": categories,
+            "missing": missing,
+            "summary": "；".join(summary_parts) if categories else "未识别到任何已知音频库文件",
+        }
+
+    def _collect_bank_info(self, mod_name):
+        # 收集语音包内可识别 .bank 文件的安装文件名、路径、大小、类型与语言，供对比使用。
+        mod_dir = self.library_dir / str(mod_name)
+        if not self._is_safe_path(mod_dir, self.library_dir) or not mod_dir.is_dir():
+            raise FileNotFoundError(f"语音包不存在: {mod_name}")
+        files = {}
+        types = set()
+        langs = set()
+        for group in self._detect_mod_files(mod_dir):
+            types.add(group["type"])
+            langs.update(group["merged_langs"])
+            for rel in group["files"]:
+                path = mod_dir / rel
+                try:
+                    size = path.stat().st_size
+                except OSError:
+                    size = 0
+                # 安装时只保留文件名，同名文件以文件名小写作为对比键
+                files[Path(rel).name.lower()] = {"file": Path(rel).name, "path": path, "size": size, "type": group["type"]}
+        return files, types, langs
+
+    @staticmethod
+    def _same_content(a: Path, b: Path) -> bool:
+        try:
+            if a.stat().st_size != b.stat().st_size:
+                return False
+            digests = []
+            for p in (a, b):
+                h = hashlib.sha256()
+                with open(p, "rb") as f:
+                    for chunk in iter(lambda: f.read(1024 * 1024), b""):
+                        h.update(chunk)
+                digests.append(h.digest())
+            return digests[0] == digests[1]
+        except OSError:
+            return False
+
+    def compare_mods(self, mod_a, mod_b):
+        """
+        对比两个语音包覆盖的语音类型、语言与 .bank 文件，并预估先装 A 再装 B 的合并结果。
+        返回格式: {"mods": [{"name", "file_count", "size", "types", "languages"}, ...],
+                  "types": {"both", "only_a", "only_b"}, "languages": {"both", "only_a", "only_b"},
+                  "files": {"only_a", "only_b", "overlap": [{"file", "type", "size_a", "size_b", "identical"}]},
+                  "merged": {"file_count", "replaced_by_b", "kept_from_a"}}
+        """
+        files_a, types_a, langs_a = self._collect_bank_info(mod_a)
+        files_b, types_b, langs_b = self._collect_bank_info(mod_b)
+
+        def _split(a, b):
+            return {"both": sorted(a & b), "only_a": sorted(a - b), "only_b": sorted(b - a)}
+
+        overlap = []
+        for key in sorted(files_a.keys() & files_b.keys()):
+            fa, fb = files_a[key], files_b[key]
+            overlap.append({
+                "file": fa["file"],
+                "type": fa["type"],
+                "size_a": fa["size"],
+                "size_b": fb["size"],
+                "identical": self._same_content(fa["path"], fb["path"]),
+            })
+
+        def _summary(name, files, types, langs):
+            return {
+                "name": name,
+                "file_count": len(files),
+                "size": sum(f["size"] for f in files.values()),
+                "types": sorted(types),
+                "languages": sorted(langs),
+            }
+
+        return {
+            "mods": [_summary(mod_a, files_a, types_a, langs_a), _summary(mod_b, files_b, types_b, langs_b)],
+            "types": _split(types_a, types_b),
+            "languages": _split(langs_a, langs_b),
+            "files": {
+                "only_a": sorted(files_a[k]["file"] for k in files_a.keys() - files_b.keys()),
+                "only_b": sorted(files_b[k]["file"] for k in files_b.keys() - files_a.keys()),
+                "overlap": overlap,
+            },
+            # 安装为同名复盖，后安装的 B 会替换 A 中内容不同的同名文件
+            "merged": {
+                "file_count": len(files_a.keys() | files_b.keys()),
+                "replaced_by_b": sum(1 for o in overlap if not o["identical"]),
+                "kept_from_a": len(files_a.keys() - files_b.keys()),
+            },
+        }
+
     @staticmethod
     def match_voice_type(filename_lower):
         """
@@ -997,6 +1212,71 @@ class LibraryManager:
                 if password is None:
                     raise ArchivePasswordCanceled("用户取消输入密码")
 
+    # 校验文件扩展名 -> (算法, 十六进制摘要长度)
+    CHECKSUM_SIDECARS = {".sha256": ("sha256", 64), ".md5": ("md5", 32)}
+
+    def _verify_checksum_sidecar(self, archive_path):
+        """
+        若压缩包旁存在同名校验文件（如 xxx.zip.sha256 或 xxx.sha256），在解压前校验摘要。
+        校验文件内容取第一个字段，兼容 sha256sum/md5sum 的输出格式。
+        不一致时抛出 ArchiveChecksumMismatch；没有校验文件时直接返回。
+        """
+        archive_path = Path(archive_path)
+        for ext, (algo, length) in self.CHECKSUM_SIDECARS.items():
+            for sidecar in (archive_path.with_name(archive_path.name + ext), archive_path.with_suffix(ext)):
+                if not sidecar.is_file():
+                    continue
+                try:
+                    fields = sidecar.read_text(encoding="utf-8", errors="ignore").split()
+                except OSError as e:
+                    self.log(f"读取校验文件失败: {sidecar.name} ({e})", "WARN")
+                    continue
+                expected = fields[0].lower() if fields else ""
+                if len(expected) != length or not re.fullmatch(r"[0-9a-f]+", expected):
+                    self.log(f"校验文件格式无效，已忽略: {sidecar.name}", "WARN")
+                    continue
+
+                h = hashlib.new(algo)
+                with open(archive_path, "rb") as f:
+                    for chunk in iter(lambda: f.read(1024 * 1024), b""):
+                        h.update(chunk)
+                if h.hexdigest() != expected:
+                    msg = f"{archive_path.name} 与 {sidecar.name} 校验不一致，文件可能下载不完整或已损坏，请重新下载"
+                    self.log(f"[ERROR] {msg}", "ERROR")
+                    self._quarantine_archive(archive_path, sidecar)
+                    raise ArchiveChecksumMismatch(msg)
+                self.log(f"[CHECK] {algo.upper()} 校验通过: {archive_path.name}", "INFO")
+                return
+
+    @property
+    def quarantine_dir(self) -> Path:
+        """校验失败的压缩包隔离目录，位于待解压区下，不会被 scan_pending 扫描到。"""
+        return self.pending_dir / DIR_QUARANTINE
+
+    def _quarantine_archive(self, archive_path, sidecar):
+        # 将待解压区中校验失败的压缩包连同校验文件移入隔离区，避免下次批量导入时反复报错；
+        # 待解压区以外的文件（例如用户直接选择的压缩包）保持原位
+        archive_path = Path(archive_path)
+        if archive_path.parent.resolve() != self.pending_dir.resolve():
+            return
+        try:
+            self.quarantine_dir.mkdir(parents=True, exist_ok=True)
+            for f in (archive_path, Path(sidecar)):
+                if f.is_file():
+                    f.replace(self.quarantine_dir / f.name)
+            self.log(f"[WARN] 已将 {archive_path.name} 移入隔离区: {self.quarantine_dir}", "WARN")
+        except OSError as e:
+            self.log(f"移入隔离区失败: {archive_path.name} ({e})", "WARN")
+
+    def _notify_imported(self, mod_name, archive_path):
+        # 导入成功后通知上层记录导入历史，回调异常不影响导入结果。
+        if not self.import_callback:
+            return
+        try:
+            self.import_callback(mod_name, Path(archive_path))
+        except Exception as e:
+            log.warning(f"记录导入历史失败: {e}")
+
     def unzip_single_zip(self, zip_path, progress_callback=None, password_provider=None):
         """
         功能定位:
@@ -1065,6 +1345,8 @@ class LibraryManager:
             if progress_callback: progress_callback(100, "跳过重复文件")
             return
 
+        self._verify_checksum_sidecar(zip_path)
+
         try:
             target_dir.mkdir()
             self.log(f"[UNZIP] 正在导入: {zip_path.name}", "UNZIP")
@@ -1078,7 +1360,9 @@ class LibraryManager:
                 password_provider=password_provider,
             )
             self._normalize_wtlive_compat_files(target_dir)
+            self._report_bank_problems(target_dir)
             self.log(f"[SUCCESS] 导入成功: {mod_name}", "SUCCESS")
+            self._notify_imported(mod_name, zip_path)
         except ArchivePasswordCanceled:
             self.log("[WARN] 已取消输入密码，导入已终止", "WARN")
             if target_dir.exists():
@@ -1096,11 +1380,11 @@ class LibraryManager:
                     pass
             raise
 
-    def unzip_zips_to_library(self, progress_callback=None, password_provider=None):
-        # 批量导入待解压区中的 ZIP/RAR 文件到语音包库，并通过回调输出总体进度。
-        zips = self.scan_pending()
+    def unzip_zips_to_library(self, progress_callback=None, password_provider=None, archives=None):
+        # 批量导入待解压区中的 ZIP/RAR 文件到语音包库，并通过回调输出总体进度；archives 为按导入规则筛选后的列表。
+        zips = self.scan_pending() if archives is None else list(archives)
         if not zips:
-            self.log("待解压区没有 ZIP/RAR 文件。", "WARN")
+            self.log("待解压区没有 ZIP/RAR 文件。" if archives is None else "待解压区没有需要导入语音包库的压缩包。", "WARN")
             if progress_callback: progress_callback(100, "没有文件")
             return
 
@@ -1109,6 +1393,7 @@ class LibraryManager:
 
         success_count = 0
         skipped_count = 0
+        checksum_failures = []
 
         for idx, zip_file in enumerate(zips):
             try:
@@ -1126,6 +1411,8 @@ class LibraryManager:
                         progress_callback(base_progress + share_progress, f"跳过: {mod_name}")
                     continue
 
+                self._verify_checksum_sidecar(zip_file)
+
                 target_dir.mkdir()
                 self.log(f"[UNZIP] 正在解压 ({idx + 1}/{total}): {zip_file.name}", "UNZIP")
 
@@ -1138,9 +1425,15 @@ class LibraryManager:
                     password_provider=password_provider,
                 )
                 self._normalize_wtlive_compat_files(target_dir)
+                self._report_bank_problems(target_dir)
 
                 success_count += 1
                 self.log(f"[SUCCESS] 解压成功: {mod_name}", "SUCCESS")
+                self._notify_imported(mod_name, zip_file)
+            except ArchiveChecksumMismatch as e:
+                checksum_failures.append(str(e))
+                if progress_callback:
+                    progress_callback(base_progress + share_progress, f"校验失败: {mod_name}")
             except ArchivePasswordCanceled:
                 self.log(f"[WARN] 已取消输入密码，跳过: {zip_file.name}", "WARN")
                 if target_dir.exists():
@@ -1161,6 +1454,8 @@ class LibraryManager:
 
         self.log(f"[INFO] 解压完成: 成功 {success_count}, 跳过 {skipped_count}", "INFO")
         if progress_callback: progress_callback(100, "全部完成")
+        if checksum_failures:
+            raise ArchiveChecksumMismatch("\n".join(checksum_failures))
 
     def _extract_zip_safely(self, zip_path, target_dir, progress_callback=None, base_progress=0, share_progress=100,
                             password=None):
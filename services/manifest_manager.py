@@ -188,6 +188,37 @@ class ManifestManager:
             log.error(f"记录安装失败: {type(e).__name__}: {e}")
             return False
     
+    def add_mod_files(self, mod_name: str, files: list[str]) -> bool:
+        """
+        向某个语音包的安装记录追加文件（例如复制国籍文件生成的文件），以便卸载时一并删除。
+        
+        Args:
+            mod_name: 语音包名称
+            files: 新写入 sound/mod 的文件名列表
+            
+        Returns:
+            是否记录成功
+        """
+        if not files:
+            return True
+        try:
+            record = self.manifest["installed_mods"].setdefault(
+                mod_name, {"files": [], "install_time": datetime.now().isoformat()}
+            )
+            for file_name in files:
+                if file_name not in record["files"]:
+                    record["files"].append(file_name)
+                self.manifest["file_map"][file_name] = mod_name
+            
+            success = self._save_manifest()
+            if success:
+                log.info(f"已追加安装记录: {mod_name} ({len(files)} 个文件)")
+            return success
+            
+        except Exception as e:
+            log.error(f"追加安装记录失败: {type(e).__name__}: {e}")
+            return False
+    
     def remove_mod_record(self, mod_name: str) -> bool:
         """
         按语音包维度移除清单记录，用于卸载或还原流程中的记录清理。
@@ -203,12 +234,10 @@ class ManifestManager:
             return True
         
         try:
-            files = self.manifest["installed_mods"][mod_name].get("files", [])
-            
-            # 仅在所有权仍指向当前语音包时，移除 file_map 映射
-            for file_name in files:
-                if self.manifest["file_map"].get(file_name) == mod_name:
-                    del self.manifest["file_map"][file_name]
+            # 移除所有权仍指向当前语音包的 file_map 映射（包括重新安装前追加、已不在文件列表中的文件）
+            file_map = self.manifest["file_map"]
+            for file_name in [name for name, owner in file_map.items() if owner == mod_name]:
+                del file_map[file_name]
             
             del self.manifest["installed_mods"][mod_name]
             
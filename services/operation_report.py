@@ -0,0 +1,115 @@
+# -*- coding: utf-8 -*-
+"""
+操作报告模组：将每次安装/还原的过程记录整理为可读的文本报告并保存，便于用户反馈问题时附上。
+
+报告内容：
+- 操作类型、语音包、游戏目录、开始时间、耗时与结果
+- 复制、内容未变化跳过、按排除规则跳过、因缺失或损坏跳过的文件
+- 复盖了其他语音包的同名文件（冲突处理结果）
+- 过程中的警告与错误
+
+报告保存在应用数据目录的 reports 下，最多保留 MAX_REPORTS 份。
+"""
+import re
+from datetime import datetime
+from pathlib import Path
+
+from utils.logger import get_logger
+from utils.utils import get_docs_data_dir
+
+log = get_logger(__name__)
+
+MAX_REPORTS = 50
+
+OPERATION_NAMES = {"install": "安装语音包", "restore": "还原纯净模式"}
+
+
+def reports_dir() -> Path:
+    return get_docs_data_dir() / "reports"
+
+
+def format_report(report: dict, app_version: str = "") -> str:
+    """将 CoreService.last_report 整理为纯文本报告。"""
+    lines = [
+        f"Aimer WT 操作报告 - {OPERATION_NAMES.get(report.get('operation'), report.get('operation', ''))}",
+        "=" * 40,
+        f"程序版本: {app_version or '未知'}",
+        f"开始时间: {report.get('started', '')}",
+        f"耗时: {report.get('duration_seconds', 0)} 秒",
+        f"结果: {'成功' if report.get('success') else '失败'}",
+    ]
+    if report.get("mod"):
+        lines.append(f"语音包: {report['mod']}")
+    lines.append(f"游戏目录: {report.get('game_root') or '未设置'}")
+    if report.get("error"):
+        lines.append(f"错误: {report['error']}")
+
+    def _section(title, items):
+        lines.append("")
+        lines.append(f"[{title}] {len(items)}")
+        lines.extend(f"  {item}" for item in items)
+
+    if report.get("operation") == "install":
+        _section("已复制", report.get("copied", []))
+        _section("内容未变化，跳过复制", report.get("unchanged", []))
+        _section("按排除规则跳过", report.get("excluded", []))
+        _section("缺失或损坏，未安装", [f"{s['file']}: {s['reason']}" for s in report.get("skipped", [])])
+        _section("复盖了其他语音包的文件", [f"{c['file']}（原属 {c['previous_owner']}）" for c in report.get("conflicts", [])])
+    else:
+        _section("已删除", report.get("removed", []))
+    _section("警告", report.get("warnings", []))
+    return "\n".join(lines) + "\n"
+
+
+def save_report(report: dict, app_version: str = "") -> Path:
+    """
+    保存报告文本，并清理超出数量上限的旧报告。
+
+    Returns:
+        报告文件路径
+    """
+    target_dir = reports_dir()
+    target_dir.mkdir(parents=True, exist_ok=True)
+    label = re.sub(r'[\\/:*?"<>|\s]+', "_", report.get("mod") or "")[:40]
+    name = "_".join(p for p in (datetime.now().strftime("%Y%m%d-%H%M%S"), report.get("operation", "op"), label) if p)
+    path = target_dir / f"{name}.txt"
+    counter = 1
+    while path.exists():
+        counter += 1
+        path = target_dir / f"{name}-{counter}.txt"
+    path.write_text(format_report(report, app_version), encoding="utf-8")
+
+    for old in list_reports()[MAX_REPORTS:]:
+        try:
+            (target_dir / old["name"]).unlink()
+        except OSError as e:
+            log.warning(f"删除旧报告失败 {old['name']}: {e}")
+    log.info(f"[REPORT] 操作报告已保存: {path.name}")
+    return path
+
+
+def list_reports() -> list[dict]:
+    """列出已保存的报告（最新在前）。"""
+    source_dir = reports_dir()
+    if not source_dir.is_dir():
+        return []
+    entries = []
+    for path in source_dir.glob("*.txt"):
+        try:
+            entries.append((path.stat(), path.name))
+        except OSError:
+            continue
+    entries.sort(key=lambda e: (e[0].st_mtime, e[1]), reverse=True)
+    return [{
+        "name": name,
+        "time": datetime.fromtimestamp(st.st_mtime).isoformat(timespec="seconds"),
+        "size": st.st_size,
+    } for st, name in entries]
+
+
+def read_report(name: str) -> str:
+    """读取报告文本，只允许读取 reports 目录下的文件。"""
+    path = reports_dir() / Path(str(name)).name
+    if path.suffix != ".txt" or not path.is_file():
+        raise FileNotFoundError(f"报告不存在: {name}")
+    return path.read_text(encoding="utf-8")
@@ -0,0 +1,219 @@
+# -*- coding: utf-8 -*-
+"""
+还原点模组：在还原纯净模式、安装语音包等会改写 sound/mod 的操作前记录当前状态，并支持回滚。
+
+还原点内容：
+- 安装清单（.manifest.json）的完整副本
+- sound/mod 中每个文件的大小、修改时间与 SHA-256
+
+还原点只记录清单与文件摘要，不复制文件本身；回滚时从语音包库中找回摘要一致的文件，
+找不到的文件会在结果中列出。还原点保存在应用数据目录的 restore_points 下，最多保留 MAX_POINTS 个。
+"""
+import copy
+import json
+import shutil
+from datetime import datetime
+from pathlib import Path
+
+from utils.logger import get_logger
+from utils.utils import get_docs_data_dir
+
+log = get_logger(__name__)
+
+MAX_POINTS = 10
+
+
+def _points_dir() -> Path:
+    return get_docs_data_dir() / "restore_points"
+
+
+def _load_point(path: Path) -> dict | None:
+    try:
+        with open(path, "r", encoding="utf-8") as f:
+            data = json.load(f)
+        return data if isinstance(data, dict) and isinstance(data.get("files"), dict) else None
+    except Exception as e:
+        log.warning(f"读取还原点失败 {path.name}: {type(e).__name__}: {e}")
+        return None
+
+
+def _point_files() -> list[Path]:
+    points_dir = _points_dir()
+    if not points_dir.is_dir():
+        return []
+    return sorted(points_dir.glob("*.json"), reverse=True)
+
+
+def _scan_mod_dir(core, previous: dict | None) -> dict:
+    # 大小与修改时间均未变化的文件沿用上一个还原点的摘要，避免每次重新计算整个目录
+    mod_dir = core.game_root / "sound" / "mod"
+    manifest_name = core.manifest_mgr.manifest_file.name
+    known = (previous or {}).get("files", {})
+    files = {}
+    if not mod_dir.is_dir():
+        return files
+    for entry in mod_dir.iterdir():
+        if not entry.is_file() or entry.name == manifest_name:
+            continue
+        st = entry.stat()
+        cached = known.get(entry.name)
+        if cached and cached.get("size") == st.st_size and cached.get("mtime") == st.st_mtime:
+            digest = cached["sha256"]
+        else:
+            digest = core._file_digest(entry)
+        files[entry.name] = {"size": st.st_size, "mtime": st.st_mtime, "sha256": digest}
+    return files
+
+
+def create_restore_point(core, reason: str) -> str:
+    """
+    记录当前 sound/mod 与安装清单的状态。
+
+    Args:
+        core: CoreService 实例（需已设置有效游戏路径）
+        reason: 触发原因，如 "安装 XXX"、"还原纯净模式"
+
+    Returns:
+        还原点 ID
+    """
+    if not core.game_root or not core.manifest_mgr:
+        raise ValueError("未设置游戏路径")
+
+    existing = _point_files()
+    previous = _load_point(existing[0]) if existing else None
+    now = datetime.now()
+    point_id = now.strftime("%Y%m%d-%H%M%S-%f")
+    point = {
+        "id": point_id,
+        "time": now.isoformat(timespec="seconds"),
+        "reason": reason,
+        "game_root": str(core.game_root),
+        "manifest": copy.deepcopy(core.manifest_mgr.manifest),
+        "files": _scan_mod_dir(core, previous),
+    }
+
+    points_dir = _points_dir()
+    points_dir.mkdir(parents=True, exist_ok=True)
+    temp_file = points_dir / f"{point_id}.tmp"
+    with open(temp_file, "w", encoding="utf-8") as f:
+        json.dump(point, f, indent=2, ensure_ascii=False)
+    temp_file.replace(points_dir / f"{point_id}.json")
+
+    for old in _point_files()[MAX_POINTS:]:
+        try:
+            old.unlink()
+        except OSError as e:
+            log.warning(f"删除旧还原点失败 {old.name}: {e}")
+
+    log.info(f"[RESTORE POINT] 已创建还原点: {reason} ({len(point['files'])} 个文件)")
+    return point_id
+
+
+def list_restore_points() -> list[dict]:
+    """
+    列出已保存的还原点（最新在前）。
+
+    Returns:
+        [{"id", "time", "reason", "game_root", "file_count", "mods"}]
+    """
+    result = []
+    for path in _point_files():
+        point = _load_point(path)
+        if not point:
+            continue
+        result.append({
+            "id": point.get("id", path.stem),
+            "time": point.get("time", ""),
+            "reason": point.get("reason", ""),
+            "game_root": point.get("game_root", ""),
+            "file_count": len(point["files"]),
+            "mods": sorted((point.get("manifest") or {}).get("installed_mods", {}).keys()),
+        })
+    return result
+
+
+def _find_in_library(core, lib_mgr, file_name: str, digest: str, owner: str) -> Path | None:
+    # 优先在清单记录的所属语音包中查找，找不到时再搜索整个语音包库
+    library_dir = lib_mgr.library_dir
+    candidates = []
+    if owner and lib_mgr._is_safe_path(library_dir / owner, library_dir):
+        candidates.append(library_dir / owner)
+    candidates.append(library_dir)
+    for root in candidates:
+        if not root.is_dir():
+            continue
+        for path in root.rglob(file_name):
+            if path.is_file() and core._file_digest(path) == digest:
+                return path
+    return None
+
+
+def rollback_restore_point(core, lib_mgr, point_id: str) -> dict:
+    """
+    将 sound/mod 与安装清单回滚到指定还原点。
+
+    - 还原点之后新增的文件会被删除
+    - 缺失或内容已变化的文件从语音包库中找回摘要一致的副本
+    - 安装清单恢复为还原点中的副本，并同步 config.blk 的 enable_mod
+
+    Returns:
+        {"restored", "removed", "unchanged", "missing": [文件名]}
+    """
+    if not core.game_root or not core.manifest_mgr:
+        raise ValueError("未设置游戏路径")
+
+    path = _points_dir() / f"{Path(str(point_id)).name}.json"
+    point = _load_point(path) if path.is_file() else None
+    if not point:
+        raise FileNotFoundError(f"还原点不存在: {point_id}")
+    if Path(point.get("game_root", "")) != core.game_root:
+        raise ValueError("还原点属于其他游戏目录，请先切换到对应的游戏路径")
+
+    # 回滚本身也会改写 sound/mod，先记录当前状态以便撤销
+    create_restore_point(core, f"回滚到 {point.get('time', point_id)} 之前")
+
+    mod_dir = core.game_root / "sound" / "mod"
+    mod_dir.mkdir(parents=True, exist_ok=True)
+    manifest_name = core.manifest_mgr.manifest_file.name
+    snapshot = point["files"]
+    file_map = (point.get("manifest") or {}).get("file_map", {})
+
+    removed = restored = unchanged = 0
+    missing = []
+    for entry in mod_dir.iterdir():
+        if entry.name == manifest_name or entry.name in snapshot:
+            continue
+        if not core._is_safe_deletion_path(entry):
+            log.warning(f"🚫 [安全拦截] 拒绝删除: {entry}")
+            continue
+        try:
+            core._remove_path(entry)
+            removed += 1
+        except OSError as e:
+            log.warning(f"回滚时删除失败 {entry.name}: {e}")
+
+    for file_name, info in snapshot.items():
+        target = mod_dir / file_name
+        try:
+            if target.is_file() and target.stat().st_size == info["size"] and core._file_digest(target) == info["sha256"]:
+                unchanged += 1
+                continue
+            source = _find_in_library(core, lib_mgr, file_name, info["sha256"], file_map.get(file_name, ""))
+            if not source:
+                missing.append(file_name)
+                continue
+            shutil.copy2(source, target)
+            restored += 1
+        except OSError as e:
+            log.warning(f"回滚文件失败 {file_name}: {e}")
+            missing.append(file_name)
+
+    core.manifest_mgr.manifest = copy.deepcopy(point.get("manifest") or core.manifest_mgr.EMPTY_MANIFEST)
+    core.manifest_mgr._save_manifest()
+    if core.manifest_mgr.manifest.get("installed_mods"):
+        core._update_config_blk()
+    else:
+        core._disable_config_mod()
+
+    log.info(f"[ROLLBACK] 已回滚到还原点 {point_id}: 找回 {restored} 个，删除 {removed} 个，无法找回 {len(missing)} 个")
+    return {"restored": restored, "removed": removed, "unchanged": unchanged, "missing": missing}
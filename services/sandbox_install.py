@@ -0,0 +1,133 @@
+# -*- coding: utf-8 -*-
+"""
+沙盒安装模组：供语音包作者在不触碰真实游戏目录的情况下测试安装行为。
+
+功能包括：
+- 在临时目录中模拟 <game_root>/config.blk 与 sound/mod 结构
+- 复用 CoreService.install_from_library 执行与正式安装相同的流程
+- 安装后逐项校验文件、同名覆盖、.bank 文件头、config.blk 与安装清单，并生成报告
+"""
+import hashlib
+import shutil
+import tempfile
+from pathlib import Path
+
+from services.core_logic import CoreService
+from wt.wt_sound import check_bank_file
+from utils.logger import get_logger
+
+log = get_logger(__name__)
+
+# 模拟游戏目录使用的最小 config.blk
+SANDBOX_CONFIG_BLK = "sound{\n  enable_mod:b=no\n}\n"
+
+
+def _file_digest(path: Path) -> str:
+    h = hashlib.sha256()
+    with open(path, "rb") as f:
+        for chunk in iter(lambda: f.read(1024 * 1024), b""):
+            h.update(chunk)
+    return h.hexdigest()
+
+
+def run_sandbox_install(lib_mgr, mod_name: str, install_list: list[str] | None = None,
+                        keep_sandbox: bool = False) -> dict:
+    """
+    将语音包安装到临时沙盒目录并执行全部校验。
+
+    Args:
+        lib_mgr: LibraryManager 实例，用于定位语音包与识别 .bank 文件
+        mod_name: 语音包库中的目录名
+        install_list: 待安装文件的相对路径列表；为空时安装语音包内全部 .bank 文件
+        keep_sandbox: 为 True 时保留沙盒目录以便作者手动检查
+
+    Returns:
+        {"passed": bool, "sandbox": str, "files": int, "checks": [{"name", "ok", "detail"}], "coverage": {...}}
+    """
+    source = lib_mgr.library_dir / str(mod_name)
+    if not lib_mgr._is_safe_path(source, lib_mgr.library_dir) or not source.is_dir():
+        raise FileNotFoundError(f"语音包不存在: {mod_name}")
+
+    if not install_list:
+        install_list = sorted(str(f.relative_to(source)).replace("\\", "/") for f in source.rglob("*")
+                              if f.is_file() and f.name.lower().endswith(".bank"))
+
+    sandbox = Path(tempfile.mkdtemp(prefix="aimerwt_sandbox_"))
+    checks = []
+
+    def check(name, ok, detail=""):
+        checks.append({"name": name, "ok": bool(ok), "detail": detail})
+
+    try:
+        (sandbox / "config.blk").write_text(SANDBOX_CONFIG_BLK, encoding="utf-8")
+        core = CoreService()
+        valid, msg = core.validate_game_path(str(sandbox))
+        if not valid:
+            raise RuntimeError(f"沙盒目录初始化失败: {msg}")
+
+        log.info(f"[SANDBOX] 开始沙盒安装: {mod_name} -> {sandbox}")
+        installed = core.install_from_library(source, install_list)
+        check("安装流程", installed, "" if installed else "install_from_library 返回失败，详见日志")
+
+        mod_dir = sandbox / "sound" / "mod"
+
+        # 安装时目录结构会被拍平，同名文件后装的会覆盖先装的
+        by_name = {}
+        for rel in install_list:
+            by_name.setdefault(Path(rel).name.lower(), []).append(rel)
+        collisions = {k: v for k, v in by_name.items() if len(v) > 1}
+        check("同名文件", not collisions,
+              "; ".join(", ".join(v) for v in collisions.values()) if collisions else "")
+
+        missing = []
+        mismatched = []
+        for rel in install_list:
+            src = source / rel
+            dest = mod_dir / Path(rel).name
+            if not src.is_file():
+                missing.append(f"{rel} (源文件不存在)")
+            elif not dest.is_file():
+                missing.append(rel)
+            elif Path(rel).name.lower() not in collisions and _file_digest(src) != _file_digest(dest):
+                mismatched.append(rel)
+        check("文件完整性", not missing and not mismatched,
+              "; ".join([f"缺失: {m}" for m in missing] + [f"内容不一致: {m}" for m in mismatched]))
+
+        corrupted = []
+        for rel in install_list:
+            if rel.lower().endswith(".bank") and (source / rel).is_file():
+                reason = check_bank_file(source / rel)
+                if reason:
+                    corrupted.append(f"{rel} ({reason})")
+        check("音频库文件头", not corrupted, "; ".join(corrupted))
+
+        unknown = [rel for rel in install_list
+                   if rel.lower().endswith(".bank") and not lib_mgr.match_voice_type(Path(rel).name.lower())]
+        check("未识别的音频库", not unknown, ", ".join(unknown))
+
+        config_text = (sandbox / "config.blk").read_text(encoding="utf-8", errors="ignore")
+        check("config.blk", "enable_mod:b=yes" in config_text,
+              "" if "enable_mod:b=yes" in config_text else "未写入 enable_mod:b=yes")
+
+        recorded = []
+        if core.manifest_mgr:
+            recorded = core.manifest_mgr.manifest.get("installed_mods", {}).get(source.name, {}).get("files", [])
+        check("安装清单", len(recorded) > 0, f"记录 {len(recorded)} 个文件")
+
+        try:
+            coverage = lib_mgr.get_coverage_report(mod_name)
+        except Exception as e:
+            coverage = {"summary": f"生成失败: {e}"}
+    finally:
+        if not keep_sandbox:
+            shutil.rmtree(sandbox, ignore_errors=True)
+
+    passed = all(c["ok"] for c in checks)
+    log.info(f"[SANDBOX] 沙盒安装完成: {mod_name}，{'全部通过' if passed else '存在问题'}")
+    return {
+        "passed": passed,
+        "sandbox": str(sandbox) if keep_sandbox else "",
+        "files": len(install_list),
+        "checks": checks,
+        "coverage": coverage,
+    }
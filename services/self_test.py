@@ -0,0 +1,175 @@
+# -*- coding: utf-8 -*-
+"""
+端到端自检模组：在临时目录中完整走一遍语音包处理流程，用于区分本机环境问题与程序缺陷。
+
+流程：
+- 生成模拟语音包并打包为 ZIP
+- 通过 LibraryManager 导入到临时语音包库并识别文件
+- 通过 CoreService 安装到模拟游戏目录并校验文件与 config.blk
+- 按安装清单卸载语音包并确认文件与安装记录已移除
+- 还原模拟游戏目录并确认已清空
+
+所有操作均在临时目录内完成，不读写用户的语音包库与游戏目录。
+"""
+import hashlib
+import json
+import os
+import platform
+import shutil
+import struct
+import sys
+import tempfile
+import time
+import zipfile
+from pathlib import Path
+
+from services.core_logic import CoreService
+from services.library_manager import LibraryManager
+from wt.wt_sound import BANK_MAGIC, BANK_FORM
+from utils.logger import get_logger
+
+log = get_logger(__name__)
+
+SELF_TEST_MOD = "AimerWT_SelfTest"
+SELF_TEST_BANKS = ("crew_dialogs_ground_ru.bank", "aircraft_engine.bank")
+
+
+class _StepFailed(Exception):
+    pass
+
+
+def _digest(path: Path) -> str:
+    return hashlib.sha256(path.read_bytes()).hexdigest()
+
+
+def run_self_test() -> dict:
+    """
+    执行端到端自检，某一步失败后跳过后续步骤。
+
+    Returns:
+        {"passed": bool, "steps": [{"name", "ok", "detail", "ms"}], "environment": {...}}
+    """
+    root = Path(tempfile.mkdtemp(prefix="aimerwt_selftest_"))
+    steps = []
+    state = {}
+
+    def step(name, func):
+        if steps and not steps[-1]["ok"]:
+            steps.append({"name": name, "ok": False, "detail": "已跳过", "ms": 0})
+            return
+        start = time.perf_counter()
+        try:
+            detail = func() or ""
+            ok = True
+        except Exception as e:
+            detail = str(e) if isinstance(e, _StepFailed) else f"{type(e).__name__}: {e}"
+            ok = False
+        steps.append({"name": name, "ok": ok, "detail": detail,
+                      "ms": int((time.perf_counter() - start) * 1000)})
+
+    def create_pack():
+        src = root / "src" / SELF_TEST_MOD
+        src.mkdir(parents=True)
+        (src / "info.json").write_text(json.dumps({"title": SELF_TEST_MOD, "author": "self-test"}), encoding="utf-8")
+        for name in SELF_TEST_BANKS:
+            body = BANK_FORM + os.urandom(64 * 1024)
+            (src / name).write_bytes(BANK_MAGIC + struct.pack("<I", len(body)) + body)
+        pending = root / "pending"
+        pending.mkdir()
+        archive = pending / f"{SELF_TEST_MOD}.zip"
+        with zipfile.ZipFile(archive, "w", zipfile.ZIP_DEFLATED) as zf:
+            for f in src.iterdir():
+                zf.write(f, f.name)
+        state["src"] = src
+        state["archive"] = archive
+        return f"{archive.stat().st_size} 字节"
+
+    def import_pack():
+        library = root / "library"
+        library.mkdir()
+        lib_mgr = LibraryManager(pending_dir=str(root / "pending"), library_dir=str(library))
+        lib_mgr.unzip_single_zip(state["archive"])
+        if not (library / SELF_TEST_MOD).is_dir():
+            raise _StepFailed("解压后未在语音包库中找到语音包目录")
+        state["lib_mgr"] = lib_mgr
+
+    def detect_pack():
+        lib_mgr = state["lib_mgr"]
+        if SELF_TEST_MOD not in lib_mgr.scan_library():
+            raise _StepFailed("扫描语音包库未发现导入的语音包")
+        files = [f for g in lib_mgr._detect_mod_files(lib_mgr.library_dir / SELF_TEST_MOD) for f in g["files"]]
+        if sorted(Path(f).name for f in files) != sorted(SELF_TEST_BANKS):
+            raise _StepFailed(f"识别到的音频库不符: {files}")
+        state["files"] = files
+        return f"识别 {len(files)} 个音频库"
+
+    def install_pack():
+        game = root / "game"
+        game.mkdir()
+        (game / "config.blk").write_text("sound{\n  enable_mod:b=no\n}\n", encoding="utf-8")
+        core = CoreService()
+        valid, msg = core.validate_game_path(str(game))
+        if not valid:
+            raise _StepFailed(f"模拟游戏目录校验失败: {msg}")
+        if not core.install_from_library(state["lib_mgr"].library_dir / SELF_TEST_MOD, state["files"]):
+            raise _StepFailed("安装流程返回失败，详见日志")
+        state["core"] = core
+        state["game"] = game
+
+    def verify_install():
+        mod_dir = state["game"] / "sound" / "mod"
+        for name in SELF_TEST_BANKS:
+            dest = mod_dir / name
+            if not dest.is_file():
+                raise _StepFailed(f"缺少已安装文件: {name}")
+            if _digest(dest) != _digest(state["src"] / name):
+                raise _StepFailed(f"文件内容不一致: {name}")
+        if "enable_mod:b=yes" not in (state["game"] / "config.blk").read_text(encoding="utf-8"):
+            raise _StepFailed("config.blk 未启用 enable_mod")
+
+    def uninstall_pack():
+        core = state["core"]
+        if not core.uninstall_mod(SELF_TEST_MOD):
+            raise _StepFailed("卸载流程返回失败，详见日志")
+        mod_dir = state["game"] / "sound" / "mod"
+        leftovers = [name for name in SELF_TEST_BANKS if (mod_dir / name).exists()]
+        if leftovers:
+            raise _StepFailed(f"卸载后仍有残留: {', '.join(leftovers)}")
+        if SELF_TEST_MOD in core.manifest_mgr.manifest.get("installed_mods", {}):
+            raise _StepFailed("卸载后安装清单中仍有记录")
+        if "enable_mod:b=yes" in (state["game"] / "config.blk").read_text(encoding="utf-8"):
+            raise _StepFailed("卸载最后一个语音包后 config.blk 仍启用 enable_mod")
+
+    def restore():
+        if not state["core"].restore_game():
+            raise _StepFailed("还原流程返回失败，详见日志")
+        mod_dir = state["game"] / "sound" / "mod"
+        leftovers = [p.name for p in mod_dir.iterdir()] if mod_dir.exists() else []
+        if leftovers:
+            raise _StepFailed(f"还原后仍有残留: {', '.join(leftovers)}")
+        if "enable_mod:b=yes" in (state["game"] / "config.blk").read_text(encoding="utf-8"):
+            raise _StepFailed("config.blk 仍启用 enable_mod")
+
+    log.info(f"[SELFTEST] 开始自检: {root}")
+    try:
+        step("生成测试语音包", create_pack)
+        step("导入压缩包", import_pack)
+        step("识别语音包", detect_pack)
+        step("安装到模拟游戏目录", install_pack)
+        step("校验安装结果", verify_install)
+        step("卸载语音包", uninstall_pack)
+        step("还原游戏目录", restore)
+    finally:
+        shutil.rmtree(root, ignore_errors=True)
+
+    passed = all(s["ok"] for s in steps)
+    log.info(f"[SELFTEST] 自检完成: {'全部通过' if passed else '存在失败步骤'}")
+    return {
+        "passed": passed,
+        "steps": steps,
+        "environment": {
+            "os": f"{platform.system()} {platform.release()}",
+            "python": sys.version.split()[0],
+            "temp_dir": tempfile.gettempdir(),
+        },
+    }
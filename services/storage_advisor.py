@@ -0,0 +1,226 @@
+# -*- coding: utf-8 -*-
+"""
+存储清理模组：统计本程序产生的可清理文件占用，并提供按类别一键清理。
+
+统计类别：
+- pending: 待解压区中的压缩包
+- quarantine: 校验失败后移入隔离区的压缩包
+- sandbox: 沙盒安装与自检遗留的临时目录
+- orphaned: 游戏 sound/mod 中不在安装清单内的文件，可能是用户手动放入的语音包，清理前必须确认
+
+本程序删除语音包时直接删除、封面按需读取不落盘，没有回收站与缩略图缓存，因此不提供这两个类别。
+
+另提供游戏缓存目录的统计与清理，缓存过期是新语音包不生效的常见原因。
+"""
+import os
+import tempfile
+from pathlib import Path
+
+from utils.logger import get_logger
+
+log = get_logger(__name__)
+
+# 沙盒安装与自检创建临时目录时使用的前缀
+TEMP_PREFIXES = ("aimerwt_sandbox_", "aimerwt_selftest_")
+
+# 游戏根目录下的缓存目录（着色器与资源缓存，游戏启动时会自动重建）
+GAME_CACHE_DIRS = ("cache",)
+
+CATEGORY_NAMES = {
+    "pending": "待解压区压缩包",
+    "quarantine": "校验失败的隔离压缩包",
+    "sandbox": "沙盒/自检临时目录",
+    "orphaned": "游戏目录中无安装记录的文件",
+}
+
+# 清理前需要用户确认的类别（可能包含用户手动放入、无法从库中恢复的文件）
+CONFIRM_CATEGORIES = ("orphaned",)
+
+
+def _path_size(path: Path) -> int:
+    if path.is_file() or path.is_symlink():
+        try:
+            return path.lstat().st_size
+        except OSError:
+            return 0
+    total = 0
+    for dirpath, _, filenames in os.walk(path):
+        for f in filenames:
+            fp = os.path.join(dirpath, f)
+            if not os.path.islink(fp):
+                try:
+                    total += os.path.getsize(fp)
+                except OSError:
+                    pass
+    return total
+
+
+def _format_size(size: int) -> str:
+    mb_size = size / (1024 * 1024)
+    if mb_size < 1:
+        return "<1 MB"
+    if mb_size >= 1024:
+        return f"{mb_size / 1024:.1f} GB"
+    return f"{int(mb_size)} MB"
+
+
+def _collect(lib_mgr, core) -> dict[str, list[Path]]:
+    items = {
+        "pending": list(lib_mgr.scan_pending()),
+        "quarantine": [],
+        "sandbox": [],
+        "orphaned": [],
+    }
+
+    try:
+        if lib_mgr.quarantine_dir.is_dir():
+            items["quarantine"] = sorted(lib_mgr.quarantine_dir.iterdir())
+    except OSError as e:
+        log.warning(f"扫描隔离区失败: {e}")
+
+    try:
+        for entry in Path(tempfile.gettempdir()).iterdir():
+            if entry.is_dir() and entry.name.startswith(TEMP_PREFIXES):
+                items["sandbox"].append(entry)
+    except OSError as e:
+        log.warning(f"扫描临时目录失败: {e}")
+
+    if core.game_root and core.manifest_mgr:
+        mod_dir = core.game_root / "sound" / "mod"
+        file_map = core.manifest_mgr.manifest.get("file_map", {})
+        manifest_name = core.manifest_mgr.manifest_file.name
+        try:
+            if mod_dir.is_dir():
+                for entry in mod_dir.iterdir():
+                    if entry.name == manifest_name or entry.name in file_map:
+                        continue
+                    items["orphaned"].append(entry)
+        except OSError as e:
+            log.warning(f"扫描 sound/mod 失败: {e}")
+
+    return items
+
+
+def analyze_storage(lib_mgr, core) -> dict:
+    """
+    统计各类别可清理文件的数量与占用空间。
+
+    Args:
+        lib_mgr: LibraryManager 实例，用于定位待解压区
+        core: CoreService 实例，用于定位游戏目录与安装清单
+
+    Returns:
+        {"categories": [{"key", "name", "count", "size", "size_str", "needs_confirm", "files"}], "total", "total_str"}
+        需要确认的类别会附带文件名列表，供前端在清理前展示
+    """
+    categories = []
+    total = 0
+    for key, paths in _collect(lib_mgr, core).items():
+        size = sum(_path_size(p) for p in paths)
+        total += size
+        needs_confirm = key in CONFIRM_CATEGORIES
+        categories.append({
+            "key": key,
+            "name": CATEGORY_NAMES[key],
+            "count": len(paths),
+            "size": size,
+            "size_str": _format_size(size),
+            "needs_confirm": needs_confirm,
+            "files": [p.name for p in paths] if needs_confirm else [],
+        })
+    return {"categories": categories, "total": total, "total_str": _format_size(total)}
+
+
+def clean_storage(lib_mgr, core, category: str, confirmed: bool = False) -> dict:
+    """
+    清理指定类别的文件，每个待删除路径都会先校验是否位于该类别的目录内。
+    CONFIRM_CATEGORIES 中的类别必须传入 confirmed=True，否则拒绝清理。
+
+    Returns:
+        {"removed": int, "failed": int, "freed": int, "freed_str": str}
+    """
+    if category not in CATEGORY_NAMES:
+        raise ValueError(f"未知的清理类别: {category}")
+    if category in CONFIRM_CATEGORIES and not confirmed:
+        raise PermissionError(f"清理「{CATEGORY_NAMES[category]}」前需要确认")
+
+    temp_root = Path(tempfile.gettempdir())
+    removed = failed = freed = 0
+    for path in _collect(lib_mgr, core)[category]:
+        if category == "pending":
+            safe = lib_mgr._is_safe_path(path, lib_mgr.pending_dir)
+        elif category == "quarantine":
+            safe = path.parent.resolve() == lib_mgr.quarantine_dir.resolve()
+        elif category == "sandbox":
+            safe = path.parent.resolve() == temp_root.resolve() and path.name.startswith(TEMP_PREFIXES)
+        else:
+            safe = core._is_safe_deletion_path(path)
+        if not safe:
+            log.warning(f"🚫 [安全拦截] 拒绝删除: {path}")
+            failed += 1
+            continue
+
+        size = _path_size(path)
+        try:
+            core._remove_path(path)
+            removed += 1
+            freed += size
+        except OSError as e:
+            log.warning(f"清理失败: {path} - {e}")
+            failed += 1
+
+    log.info(f"[CLEAN] {CATEGORY_NAMES[category]}: 已删除 {removed} 项，释放 {_format_size(freed)}")
+    return {"removed": removed, "failed": failed, "freed": freed, "freed_str": _format_size(freed)}
+
+
+def _game_cache_dirs(core) -> list[Path]:
+    if not core.game_root:
+        return []
+    return [core.game_root / name for name in GAME_CACHE_DIRS if (core.game_root / name).is_dir()]
+
+
+def analyze_game_cache(core) -> dict:
+    """
+    统计游戏缓存目录的占用。
+
+    Returns:
+        {"dirs": [{"path", "size", "size_str"}], "total", "total_str"}
+    """
+    dirs = []
+    total = 0
+    for path in _game_cache_dirs(core):
+        size = _path_size(path)
+        total += size
+        dirs.append({"path": str(path), "size": size, "size_str": _format_size(size)})
+    return {"dirs": dirs, "total": total, "total_str": _format_size(total)}
+
+
+def clear_game_cache(core) -> dict:
+    """
+    清空游戏缓存目录的内容（保留目录本身），仅处理 GAME_CACHE_DIRS 中列出的目录。
+
+    Returns:
+        {"removed": int, "failed": int, "freed": int, "freed_str": str}
+    """
+    if not core.game_root:
+        raise ValueError("未设置游戏路径")
+
+    game_root = core.game_root.resolve()
+    removed = failed = freed = 0
+    for cache_dir in _game_cache_dirs(core):
+        if cache_dir.resolve().parent != game_root:
+            log.warning(f"🚫 [安全拦截] 拒绝删除: {cache_dir}")
+            failed += 1
+            continue
+        for entry in cache_dir.iterdir():
+            size = _path_size(entry)
+            try:
+                core._remove_path(entry)
+                removed += 1
+                freed += size
+            except OSError as e:
+                log.warning(f"清理缓存失败: {entry} - {e}")
+                failed += 1
+
+    log.info(f"[CLEAN] 游戏缓存: 已删除 {removed} 项，释放 {_format_size(freed)}")
+    return {"removed": removed, "failed": failed, "freed": freed, "freed_str": _format_size(freed)}
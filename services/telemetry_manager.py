@@ -7,6 +7,7 @@
 - 获取机器唯一标识码 (HWID)，用于统计跨平台用户数量。
 - 在本地完成硬件指纹聚合与哈希，确保用户隐私（非直传原始序列号）。
 - 异步上报系统详情，帮助开发者了解用户分布与环境特征。
+- 随心跳批量上传警告/错误日志，便于按版本定位问题。
 
 安全性审计:
 - 隐私性：收集的 CPU/磁盘 ID 仅用于生成哈希，不以明文形式离线或上传。
@@ -14,18 +15,44 @@
 - 合规性：加盐哈希（Salted Hash）防止 HWID 被轻易碰撞且无法逆向还原原始硬件码。
 """
 
+import atexit
 import hashlib
+import logging
 import os
 import platform
 import subprocess
 import sys
 import threading
 import uuid
+from datetime import datetime
 from typing import Optional
 
 import requests
 
 
+# 本地最多缓存的待上传日志条数，超出时丢弃最旧的
+LOG_BUFFER_LIMIT = 500
+# 单次上传的最大条数，与服务端 maxLogLinesPerBatch 保持一致
+LOG_BATCH_SIZE = 200
+
+
+class _LogUploadHandler(logging.Handler):
+    """收集 WARNING 及以上级别的应用日志，等待心跳时批量上传"""
+
+    def __init__(self, manager):
+        super().__init__(level=logging.WARNING)
+        self._manager = manager
+
+    def emit(self, record: logging.LogRecord) -> None:
+        # 遥测线程自身的日志不上传，避免上传失败时循环产生日志
+        if record.threadName.startswith("Telemetry"):
+            return
+        try:
+            self._manager._buffer_log(record, self.format(record))
+        except Exception:
+            pass
+
+
 class TelemetryManager:
     def __init__(self, app_version: str, report_url: Optional[str] = None):
         self._stop_heartbeat = None
@@ -48,6 +75,14 @@ class TelemetryManager:
         self._msg_callback = None
         self._cmd_callback = None
         self._log_callback = None
+        self._features = {}
+        self._experiments = {}
+        self._compat = {}
+        self._install_source = self._detect_install_source()
+        self._game_version_provider = None
+        self._log_handler = None
+        self._log_buffer = []
+        self._log_lock = threading.Lock()
 
     def set_server_message_callback(self, callback):
         """设置接收服务端控制消息的回调函数 (config: dict) -> None"""
@@ -57,6 +92,18 @@ class TelemetryManager:
         """设置接收特定用户指令的回调函数 (command: str) -> None"""
         self._cmd_callback = callback
 
+    def set_game_version_provider(self, provider):
+        """设置获取当前游戏版本号的函数 () -> str，每次上报时调用"""
+        self._game_version_provider = provider
+
+    def _get_game_version(self) -> str:
+        if not self._game_version_provider:
+            return ""
+        try:
+            return (self._game_version_provider() or "")[:32]
+        except Exception:
+            return ""
+
     def set_log_callback(self, callback):
         """设置日志回调 (msg: str, level: str) -> None"""
         self._log_callback = callback
@@ -154,6 +201,25 @@ class TelemetryManager:
         raw_hwid = f"{cpu_id}|{disk_id}|{mac_addr}|{hostname}|{salt}"
         return hashlib.sha256(raw_hwid.encode('utf-8')).hexdigest()
 
+    def _detect_install_source(self) -> str:
+        """
+        安装来源（例如 github / forum / mirror），由发布包内的 install_source.txt 或 app_secrets.INSTALL_SOURCE 提供。
+        服务端只记录首次上报的值。
+        """
+        base_dir = os.path.dirname(sys.executable) if getattr(sys, "frozen", False) else os.path.dirname(
+            os.path.dirname(os.path.abspath(__file__)))
+        source = ""
+        try:
+            with open(os.path.join(base_dir, "install_source.txt"), "r", encoding="utf-8") as f:
+                source = f.read().strip()
+        except OSError:
+            try:
+                import app_secrets
+                source = getattr(app_secrets, "INSTALL_SOURCE", "") or ""
+            except ImportError:
+                pass
+        return source.lower()[:32]
+
     def get_machine_id(self) -> str:
         return self._machine_id
 
@@ -201,7 +267,9 @@ class TelemetryManager:
                     "screen_res": screen_res,
                     "python_version": sys.version.split()[0],
                     "locale": user_locale,
-                    "session_id": os.getpid()
+                    "session_id": os.getpid(),
+                    "source": self._install_source,
+                    "game_version": self._get_game_version()
                 }
 
                 response = requests.post(
@@ -215,13 +283,25 @@ class TelemetryManager:
                     self._is_log_error = False
                     try:
                         data = response.json()
+                        compat = data.get("compat")
+                        if isinstance(compat, dict):
+                            self._compat = compat
+
                         sys_config = data.get("sys_config")
                         if sys_config and self._msg_callback:
                             self._msg_callback(sys_config)
 
+                        features = data.get("features")
+                        if isinstance(features, dict):
+                            self._features = features
+
+                        experiments = data.get("experiments")
+                        if isinstance(experiments, dict):
+                            self._experiments = experiments
+
                         user_cmd = data.get("user_command")
                         if user_cmd and self._cmd_callback:
-                            self._cmd_callback(user_cmd)
+                            self._run_user_command(user_cmd, data.get("command_id"))
                     except Exception:
                         pass
                 else:
@@ -243,20 +323,233 @@ class TelemetryManager:
         """
         self._stop_heartbeat = threading.Event()
 
+        self._attach_log_handler()
+
         def _loop():
             while not self._stop_heartbeat.wait(60):
                 try:
                     self.report_startup()
+                    self.flush_logs()
                 except Exception:
                     pass
 
         thread = threading.Thread(target=_loop, name="TelemetryHeartbeat", daemon=True)
         thread.start()
 
+    def _attach_log_handler(self):
+        """挂载日志收集器到应用根记录器，重复调用不会重复挂载"""
+        if self._log_handler:
+            return
+        from utils.logger import APP_LOGGER_NAME
+        self._log_handler = _LogUploadHandler(self)
+        self._log_handler.setFormatter(logging.Formatter("%(name)s: %(message)s"))
+        logging.getLogger(APP_LOGGER_NAME).addHandler(self._log_handler)
+
+    def _detach_log_handler(self):
+        if not self._log_handler:
+            return
+        from utils.logger import APP_LOGGER_NAME
+        logging.getLogger(APP_LOGGER_NAME).removeHandler(self._log_handler)
+        self._log_handler = None
+        with self._log_lock:
+            self._log_buffer.clear()
+
+    def _buffer_log(self, record: logging.LogRecord, message: str):
+        level = "error" if record.levelno >= logging.ERROR else "warn"
+        line = {
+            "level": level,
+            "message": message[:2000],
+            "time": datetime.fromtimestamp(record.created).astimezone().isoformat(timespec="seconds"),
+        }
+        with self._log_lock:
+            self._log_buffer.append(line)
+            if len(self._log_buffer) > LOG_BUFFER_LIMIT:
+                del self._log_buffer[:len(self._log_buffer) - LOG_BUFFER_LIMIT]
+
+    def flush_logs(self, timeout: int = 10):
+        """
+        将缓存的警告/错误日志分批上传到服务端 /logs，同步执行，失败时放回缓存等待下次重试。
+        """
+        if not self.report_url:
+            return
+        with self._log_lock:
+            pending, self._log_buffer = self._log_buffer, []
+
+        while pending:
+            batch = pending[:LOG_BATCH_SIZE]
+            try:
+                response = requests.post(
+                    self._endpoint("logs"),
+                    json={"machine_id": self._machine_id, "version": self.app_version, "lines": batch},
+                    timeout=timeout,
+                    headers={'User-Agent': f'AimerWT-Client/{self.app_version} ({platform.system()})'}
+                )
+                # 4xx 表示被服务端拒收 (例如已被封禁)，丢弃而不是反复重试
+                if response.status_code >= 500:
+                    raise RuntimeError(response.status_code)
+            except Exception:
+                with self._log_lock:
+                    self._log_buffer[:0] = pending
+                    if len(self._log_buffer) > LOG_BUFFER_LIMIT:
+                        del self._log_buffer[:len(self._log_buffer) - LOG_BUFFER_LIMIT]
+                return
+            pending = pending[LOG_BATCH_SIZE:]
+
+    def _endpoint(self, name: str) -> str:
+        """基于上报地址推导同一服务下的其他接口地址 (例如 /ack)"""
+        base = self.report_url.rsplit("/", 1)[0]
+        return f"{base}/{name}"
+
+    def _run_user_command(self, cmd: str, command_id=None):
+        """执行服务端下发的指令，并将执行结果回传给服务端用于统计指令送达率。"""
+        try:
+            self._cmd_callback(cmd)
+            self.ack_command(command_id, "executed")
+        except Exception as e:
+            self.ack_command(command_id, "failed", f"{type(e).__name__}: {e}")
+
+    def ack_command(self, command_id, status: str, error: str = ""):
+        """
+        异步回执: 告知服务端指令的执行结果 (executed / failed)，失败静默。
+        """
+        if not self.report_url or not command_id:
+            return
+
+        def _do_ack():
+            try:
+                requests.post(
+                    self._endpoint("command-ack"),
+                    json={"machine_id": self._machine_id, "command_id": command_id,
+                          "status": status, "error": error[:500]},
+                    timeout=10,
+                    headers={'User-Agent': f'AimerWT-Client/{self.app_version} ({platform.system()})'}
+                )
+            except Exception:
+                pass
+
+        threading.Thread(target=_do_ack, daemon=True, name="TelemetryCommandAck").start()
+
+    def ack_announcement(self, announcement_id):
+        """
+        异步回执: 告知服务端该公告/通知已在客户端展示，失败静默。
+        """
+        if not self.report_url or not announcement_id:
+            return
+
+        def _do_ack():
+            try:
+                requests.post(
+                    self._endpoint("ack"),
+                    json={"machine_id": self._machine_id, "announcement_id": announcement_id},
+                    timeout=10,
+                    headers={'User-Agent': f'AimerWT-Client/{self.app_version} ({platform.system()})'}
+                )
+            except Exception:
+                pass
+
+        threading.Thread(target=_do_ack, daemon=True, name="TelemetryAck").start()
+
+    def report_session_end(self):
+        """
+        上报本次会话结束（进程退出时调用），用于统计会话时长；同步发送但超时很短，失败静默。
+        """
+        if not self.report_url:
+            return
+        try:
+            requests.post(
+                self._endpoint("session-event"),
+                json={"machine_id": self._machine_id, "session_id": os.getpid(),
+                      "version": self.app_version, "event": "end"},
+                timeout=3,
+                headers={'User-Agent': f'AimerWT-Client/{self.app_version} ({platform.system()})'}
+            )
+        except Exception:
+            pass
+
+    def _collect_diagnostics(self, max_lines: int = 300) -> str:
+        """收集诊断包：基础系统信息 + 最近的应用日志"""
+        lines = [
+            f"version: {self.app_version}",
+            f"os: {platform.system()} {platform.release()} ({platform.version()})",
+            f"arch: {platform.machine()}",
+            f"python: {sys.version.split()[0]}",
+            "",
+        ]
+        try:
+            from utils.utils import get_docs_data_dir
+            log_file = get_docs_data_dir() / "logs" / "app.log"
+            with open(log_file, "r", encoding="utf-8", errors="replace") as f:
+                lines.extend(line.rstrip("\n") for line in f.readlines()[-max_lines:])
+        except Exception as e:
+            lines.append(f"(读取日志失败: {type(e).__name__})")
+        return "\n".join(lines)
+
+    def submit_feedback(self, category: str, message: str, include_diagnostics: bool = False) -> dict:
+        """
+        同步提交用户反馈，可选附带诊断包；返回 {"success": bool, "msg": str}。
+        """
+        if not self.report_url:
+            return {"success": False, "msg": "未配置反馈服务地址"}
+
+        payload = {
+            "machine_id": self._machine_id,
+            "version": self.app_version,
+            "category": category,
+            "message": message,
+        }
+        if include_diagnostics:
+            payload["diagnostics"] = self._collect_diagnostics()
+
+        try:
+            response = requests.post(
+                self._endpoint("feedback"),
+                json=payload,
+                timeout=15,
+                headers={'User-Agent': f'AimerWT-Client/{self.app_version} ({platform.system()})'}
+            )
+            if response.status_code == 200:
+                return {"success": True, "msg": "反馈已提交，感谢支持"}
+            return {"success": False, "msg": f"提交失败: {response.status_code}"}
+        except Exception as e:
+            return {"success": False, "msg": f"提交失败: {type(e).__name__}"}
+
+    def get_compat_policy(self) -> dict:
+        """服务端下发的版本兼容策略 (supported / force_update / unsafe / message / update_url)"""
+        return dict(self._compat)
+
+    def is_feature_enabled(self, name: str) -> bool:
+        """查询服务端下发的功能开关，未下发或未知的开关视为关闭"""
+        return bool(self._features.get(name, False))
+
+    def get_experiment_variant(self, name: str) -> str:
+        """查询服务端分配的实验分组，未参与实验时返回空字符串"""
+        return self._experiments.get(name, "")
+
+    def track_experiment_event(self, experiment: str, kind: str, goal: str = ""):
+        """
+        异步上报实验曝光 (exposure) 或转化 (conversion) 事件，失败静默。
+        """
+        if not self.report_url or experiment not in self._experiments:
+            return
+
+        def _do_track():
+            try:
+                requests.post(
+                    self._endpoint("experiment-event"),
+                    json={"machine_id": self._machine_id, "experiment": experiment, "kind": kind, "goal": goal},
+                    timeout=10,
+                    headers={'User-Agent': f'AimerWT-Client/{self.app_version} ({platform.system()})'}
+                )
+            except Exception:
+                pass
+
+        threading.Thread(target=_do_track, daemon=True, name="TelemetryExperiment").start()
+
     def stop(self):
-        """停止心跳上报"""
+        """停止心跳上报与日志上传"""
         if self._stop_heartbeat:
             self._stop_heartbeat.set()
+        self._detach_log_handler()
 
 
 _instance = None
@@ -272,6 +565,8 @@ def init_telemetry(version: str, url: str = None):
 
         _instance.report_startup()
         _instance.start_heartbeat_loop()
+        atexit.register(_instance.report_session_end)
+        atexit.register(_instance.flush_logs, 3)
     return _instance
 
 
@@ -280,3 +575,59 @@ def get_hwid():
     if _instance:
         return _instance.get_machine_id()
     return "UNKNOWN"
+
+
+def ack_announcement(announcement_id):
+    """上报公告已展示回执，遥测未初始化时忽略。"""
+    if _instance:
+        _instance.ack_announcement(announcement_id)
+
+
+def get_compat_policy() -> dict:
+    """获取版本兼容策略，遥测未初始化时返回空字典。"""
+    if _instance:
+        return _instance.get_compat_policy()
+    return {}
+
+
+def is_version_unsafe() -> bool:
+    """当前版本是否被服务端标记为问题版本（需禁用安装操作）。"""
+    return bool(get_compat_policy().get("unsafe"))
+
+
+def is_update_required() -> bool:
+    """当前版本是否低于最低支持版本且服务端要求强制更新。"""
+    compat = get_compat_policy()
+    return compat.get("supported") is False and bool(compat.get("force_update"))
+
+
+def is_install_blocked() -> bool:
+    """问题版本或需强制更新的版本均禁用安装操作。"""
+    return is_version_unsafe() or is_update_required()
+
+
+def is_feature_enabled(name: str) -> bool:
+    """查询远程功能开关，遥测未初始化时一律视为关闭。"""
+    if _instance:
+        return _instance.is_feature_enabled(name)
+    return False
+
+
+def get_experiment_variant(name: str) -> str:
+    """查询实验分组，遥测未初始化时返回空字符串。"""
+    if _instance:
+        return _instance.get_experiment_variant(name)
+    return ""
+
+
+def track_experiment_event(experiment: str, kind: str, goal: str = ""):
+    """上报实验事件，遥测未初始化时忽略。"""
+    if _instance:
+        _instance.track_experiment_event(experiment, kind, goal)
+
+
+def submit_feedback(category: str, message: str, include_diagnostics: bool = False) -> dict:
+    """提交用户反馈，遥测未初始化时返回失败。"""
+    if _instance:
+        return _instance.submit_feedback(category, message, include_diagnostics)
+    return {"success": False, "msg": "遥测服务未初始化，无法提交反馈"}
@@ -891,6 +891,8 @@ const app = {
         const el = document.getElementById(modalId);
         if (!el) return;
         if (!el.classList.contains('show')) return;
+        // 强制更新提示锁定后不允许关闭
+        if (el.dataset.locked === '1') return;
 
         el.classList.add('hiding');
 
@@ -1087,6 +1089,7 @@ const app = {
 
     forceHideAllModals() {
         document.querySelectorAll('.modal-overlay').forEach(el => {
+            if (el.dataset.locked === '1') return;
             el.classList.remove('show');
             el.classList.remove('hiding');
         });
@@ -1263,6 +1266,8 @@ const app = {
             alert(message);
             return;
         }
+        // 强制更新提示优先，不被其它提示覆盖
+        if (modal.dataset.locked === '1') return;
 
         const titleEl = document.getElementById('alert-title');
         const msgEl = document.getElementById('alert-message');
@@ -1304,6 +1309,16 @@ const app = {
         modal.classList.add('show');
     },
 
+    // 强制更新提示：不可关闭，只保留前往下载按钮
+    showForceUpdate(title, message, linkUrl = null) {
+        this.showAlert(title, message, 'error', linkUrl);
+        const modal = document.getElementById('modal-alert');
+        if (!modal) return;
+        const okBtn = document.getElementById('alert-ok-btn');
+        if (okBtn) okBtn.style.display = 'none';
+        modal.dataset.locked = '1';
+    },
+
     // 动态更新首页公告栏文字
     updateNoticeBar(contentHtml) {
         const container = document.querySelector('.notice-content');
@@ -1749,6 +1764,10 @@ const app = {
                     <i class="ri-delete-bin-line"></i>
                 </div>
 
+                ${isInstalled ? `<div class="action-icon action-btn-del" onclick="app.uninstallMod('${mod.id}')" title="从游戏目录卸载">
+                    <i class="ri-eject-line"></i>
+                </div>` : ''}
+
                 <div style="flex:1"></div>
 
                 <div class="action-icon ${clsVideo}" onclick="${actVideo}" title="观看介绍视频">
@@ -1975,6 +1994,17 @@ const app = {
         }
     },
 
+    async uninstallMod(modId) {
+        const yes = await app.confirm(
+            '卸载确认',
+            `确定要从游戏目录卸载语音包 <strong>[${modId}]</strong> 吗？<br>仅删除该语音包安装的文件，其他语音包不受影响。`,
+            true
+        );
+        if (yes) {
+            await pywebview.api.uninstall_mod(modId);
+        }
+    },
+
     // --- 安装模态框 ---
     // openInstallModal 的实现在文件末尾，使用 modCache
 
@@ -1994,6 +2024,11 @@ const app = {
         console.log("Restore Success");
         this.installedModIds = [];
         if (this.modCache) this.renderList(this.modCache);
+    },
+
+    onUninstallSuccess(modId) {
+        this.installedModIds = (this.installedModIds || []).filter(id => id !== modId);
+        if (this.modCache) this.renderList(this.modCache);
     }
 };
 
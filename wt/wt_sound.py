@@ -1,3 +1,4 @@
+import struct
 from enum import Enum
 
 
@@ -80,3 +81,73 @@ class VoiceType(Enum):
         self.code = code
         self.chinese_name = chinese_name
         self.tag = tag
+
+
+# 语音类型所属的游戏内容类别，用于生成语音包覆盖范围报告
+COVERAGE_CATEGORIES = {
+    "ground": "陆战",
+    "air": "空战",
+    "naval": "海战",
+    "infantry": "步兵",
+    "radio": "无线电",
+    "common": "通用",
+}
+
+VOICE_TYPE_CATEGORY = {
+    VoiceType.MASTERBANK: "common",
+    VoiceType.DIALOGS_CHAT: "radio",
+    VoiceType.CREW_DIALOGS_COMMON: "radio",
+    VoiceType.CREW_DIALOGS_GROUND: "ground",
+    VoiceType.CREW_DIALOGS_NAVAL: "naval",
+    VoiceType.TANK_AMBIENT: "ground",
+    VoiceType.TANK_EFFECTS: "ground",
+    VoiceType.TANK_EFFECTS_RADIO: "ground",
+    VoiceType.TANK_ENGINES: "ground",
+    VoiceType.TANK_EXPLOSIONS: "ground",
+    VoiceType.TANK_OBJECT_CRASH: "ground",
+    VoiceType.TANK_WEAPONS: "ground",
+    VoiceType.AIRCRAFT_AMBIENT: "air",
+    VoiceType.AIRCRAFT_COMMON: "air",
+    VoiceType.AIRCRAFT_EFFECT: "air",
+    VoiceType.AIRCRAFT_ENGINE: "air",
+    VoiceType.AIRCRAFT_GUI: "air",
+    VoiceType.AIRCRAFT_GUNS: "air",
+    VoiceType.AIRCRAFT_MUSIC: "air",
+    VoiceType.SHIPS_AMBIENT: "naval",
+    VoiceType.SHIPS_EFFECTS: "naval",
+    VoiceType.SHIPS_ENGINES: "naval",
+    VoiceType.SHIPS_EXPLOSIONS: "naval",
+    VoiceType.SHIPS_WEAPONS: "naval",
+    VoiceType.INFANTRY: "infantry",
+    VoiceType.INFANTRY_AMBIENT: "infantry",
+    VoiceType.INFANTRY_EFFECT: "infantry",
+}
+
+
+# FMOD .bank 为 RIFF 容器: "RIFF" + 小端 uint32 块长度 + "FEV " 形式标识
+BANK_MAGIC = b"RIFF"
+BANK_FORM = b"FEV "
+BANK_HEADER_SIZE = 12
+
+
+def check_bank_file(path) -> str:
+    """
+    校验 .bank 文件头与长度，返回问题描述；文件正常时返回空字符串。
+    下载中断导致的截断文件会表现为 RIFF 声明长度大于实际文件长度。
+    """
+    try:
+        with open(path, "rb") as f:
+            header = f.read(BANK_HEADER_SIZE)
+            f.seek(0, 2)
+            size = f.tell()
+    except OSError as e:
+        return f"无法读取: {e}"
+
+    if len(header) < BANK_HEADER_SIZE:
+        return "文件过小"
+    if header[:4] != BANK_MAGIC or header[8:12] != BANK_FORM:
+        return "不是有效的 FMOD bank 文件"
+    declared = struct.unpack("<I", header[4:8])[0] + 8
+    if declared > size:
+        return f"文件不完整 (应为 {declared} 字节，实际 {size} 字节)"
+    return ""